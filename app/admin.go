@@ -0,0 +1,203 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/candinya/rsshub-smart-layer/modules"
+	"github.com/candinya/rsshub-smart-layer/modules/translate"
+	"github.com/candinya/rsshub-smart-layer/modules/translate/providers"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+func (a *app) adminTranslateQueue(c echo.Context) error {
+	stats := make(map[string]translate.LimitStats)
+	for name, tp := range a.tp {
+		stats[name] = tp.Stats()
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+func (a *app) adminTranslateProviders(c echo.Context) error {
+	return c.JSON(http.StatusOK, providers.List())
+}
+
+func (a *app) adminTranslateJobs(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	depth, err := a.redis.LLen(ctx, a.translateQueueKey()).Result()
+	if err != nil {
+		a.l.Error("failed to get translate queue depth", zap.Error(err))
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	counts, err := a.redis.HGetAll(ctx, a.translateJobStatsKey()).Result()
+	if err != nil {
+		a.l.Error("failed to get translate job stats", zap.Error(err))
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	stats := translateJobStats{
+		Depth:   depth,
+		Workers: a.cfg.Translate.Async.Workers,
+		Counts:  make(map[string]int64),
+	}
+	for status, count := range counts {
+		stats.Counts[status], _ = strconv.ParseInt(count, 10, 64)
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+func (a *app) adminTranslateJob(c echo.Context) error {
+	cacheKey := c.QueryParam("key")
+	if cacheKey == "" {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	status, err := a.redis.Get(c.Request().Context(), a.translateJobKey(cacheKey)).Result()
+	if errors.Is(err, redis.Nil) {
+		return c.NoContent(http.StatusNotFound)
+	} else if err != nil {
+		a.l.Error("failed to get translate job status", zap.Error(err))
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"key":    cacheKey,
+		"status": status,
+	})
+}
+
+func (a *app) adminTranslateUsage(c echo.Context) error {
+	provider := c.QueryParam("provider")
+	if provider == "" {
+		provider = a.cfg.Translate.Provider
+	}
+
+	period := c.QueryParam("period")
+	if period != modules.UsagePeriodMonth {
+		period = modules.UsagePeriodDay
+	}
+
+	// Date in YYYY-MM-DD, or today by default
+	date := time.Now()
+	if dateStr := c.QueryParam("date"); dateStr != "" {
+		var err error
+		date, err = time.Parse(time.DateOnly, dateStr)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "invalid date, should be YYYY-MM-DD")
+		}
+	}
+
+	usage, err := a.um.Usage(c.Request().Context(), provider, period, date)
+	if err != nil {
+		a.l.Error("failed to get translate usage", zap.Error(err))
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	usage.State = a.um.Check(c.Request().Context(), provider).String()
+
+	return c.JSON(http.StatusOK, usage)
+}
+
+type adminTranslateOverrideBody struct {
+	Text string `json:"text"`
+}
+
+// bindTranslateOverrideQuery: Item part is specified by platform, id, part and lang query parameters
+func bindTranslateOverrideQuery(c echo.Context) (*translateOverride, bool) {
+	override := &translateOverride{
+		Platform: c.QueryParam("platform"),
+		ID:       c.QueryParam("id"),
+		Part:     c.QueryParam("part"),
+		Lang:     c.QueryParam("lang"),
+	}
+
+	if override.Platform == "" || override.ID == "" || override.Part == "" || override.Lang == "" {
+		return nil, false
+	}
+
+	return override, true
+}
+
+func (a *app) adminTranslateOverrideGet(c echo.Context) error {
+	query, ok := bindTranslateOverrideQuery(c)
+	if !ok {
+		return c.String(http.StatusBadRequest, "platform, id, part and lang are required")
+	}
+
+	ctx := c.Request().Context()
+
+	override, err := a.getTranslateOverride(ctx, query.Platform, query.ID, query.Part, query.Lang)
+	if err != nil {
+		a.l.Error("failed to get translate override", zap.Error(err))
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	cacheKey, cached, err := a.getIndexedTranslation(ctx, query.Platform, query.ID, query.Part, query.Lang)
+	if err != nil {
+		a.l.Error("failed to get indexed translation", zap.Error(err))
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"override":  override,
+		"cache_key": cacheKey,
+		"cached":    cached,
+	})
+}
+
+func (a *app) adminTranslateOverridePut(c echo.Context) error {
+	override, ok := bindTranslateOverrideQuery(c)
+	if !ok {
+		return c.String(http.StatusBadRequest, "platform, id, part and lang are required")
+	}
+
+	var body adminTranslateOverrideBody
+	if err := c.Bind(&body); err != nil || body.Text == "" {
+		return c.String(http.StatusBadRequest, "text is required")
+	}
+	override.Text = body.Text
+
+	err := a.setTranslateOverride(c.Request().Context(), override)
+	if err != nil {
+		a.l.Error("failed to set translate override", zap.Error(err))
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	a.l.Info("translate override set", zap.String("platform", override.Platform), zap.String("id", override.ID), zap.String("part", override.Part), zap.String("lang", override.Lang))
+	return c.JSON(http.StatusOK, override)
+}
+
+func (a *app) adminTranslateOverrideDelete(c echo.Context) error {
+	query, ok := bindTranslateOverrideQuery(c)
+	if !ok {
+		return c.String(http.StatusBadRequest, "platform, id, part and lang are required")
+	}
+
+	deleted, err := a.deleteTranslateOverride(c.Request().Context(), query.Platform, query.ID, query.Part, query.Lang)
+	if err != nil {
+		a.l.Error("failed to delete translate override", zap.Error(err))
+		return c.NoContent(http.StatusInternalServerError)
+	} else if !deleted {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	a.l.Info("translate override deleted", zap.String("platform", query.Platform), zap.String("id", query.ID), zap.String("part", query.Part), zap.String("lang", query.Lang))
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (a *app) adminTranslateOverrides(c echo.Context) error {
+	overrides, err := a.listTranslateOverrides(c.Request().Context())
+	if err != nil {
+		a.l.Error("failed to list translate overrides", zap.Error(err))
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, overrides)
+}
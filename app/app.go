@@ -1,6 +1,7 @@
 package app
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 
@@ -21,7 +22,10 @@ type app struct {
 	redis *redis.Client
 
 	lb *modules.LoadBalancer
-	tp translate.Provider
+	tp map[string]*translate.Limited // By provider name
+	tm map[string]*translate.Masker  // By rule key, empty for default
+	um *modules.UsageMeter
+	tv *translate.Validator
 	ip *modules.ImageProxy
 
 	e *echo.Echo
@@ -59,15 +63,79 @@ func Start(cfg *types.Config) error {
 
 	// Initialize translator
 	if cfg.Translate != nil {
-		a.tp, err = providers.NewTranslator(cfg.Translate, a.l)
+		// Default provider is named by its type, together with additional named providers
+		providerCfgs := map[string]types.ConfigTranslateProvider{
+			cfg.Translate.Provider: {
+				Provider: cfg.Translate.Provider,
+				Settings: cfg.Translate.Settings,
+				Limit:    cfg.Translate.Limit,
+				Budget:   cfg.Translate.Budget,
+			},
+		}
+		for name, providerCfg := range cfg.Translate.Providers {
+			if _, ok := providerCfgs[name]; ok {
+				return fmt.Errorf("duplicated translate provider name: %s", name)
+			}
+			providerCfgs[name] = providerCfg
+		}
+
+		a.tp = make(map[string]*translate.Limited)
+		budgets := make(map[string]types.ConfigTranslateBudget)
+		for name, providerCfg := range providerCfgs {
+			tp, err := providers.NewTranslator(providerCfg.Provider, providerCfg.Settings, a.l)
+			if err != nil {
+				return fmt.Errorf("failed to initialize translator %s: %w", name, err)
+			}
+			a.tp[name] = translate.NewLimited(tp, &providerCfg.Limit, a.l)
+			budgets[name] = providerCfg.Budget
+		}
+
+		// Check providers used by rules and fallbacks
+		for key, rule := range cfg.Translate.Rules {
+			if _, ok := a.tp[rule.Provider]; rule.Provider != "" && !ok {
+				return fmt.Errorf("unknown translate provider %s in rule %s", rule.Provider, key)
+			}
+		}
+		for _, name := range cfg.Translate.Fallbacks {
+			if _, ok := a.tp[name]; !ok {
+				return fmt.Errorf("unknown translate fallback provider %s", name)
+			}
+		}
+
+		a.tv, err = translate.NewValidator(&cfg.Translate.Validate)
+		if err != nil {
+			return fmt.Errorf("failed to initialize translate validator: %w", err)
+		}
+
+		a.um = modules.NewUsageMeter(a.redis, cfg.System.Redis.Prefix, budgets, a.l)
+
+		a.tm, err = newTranslateMaskers(cfg.Translate)
 		if err != nil {
-			return fmt.Errorf("failed to initialize translator: %w", err)
+			return fmt.Errorf("failed to initialize translate rules: %w", err)
+		}
+
+		// Start prewarm scheduler
+		if cfg.Translate.Prewarm != nil && cfg.Translate.Prewarm.Interval > 0 && len(cfg.Translate.Prewarm.Feeds) > 0 {
+			go a.prewarmLoop()
+		}
+
+		// Start async translate workers
+		if cfg.Translate.Async.Enable {
+			if cfg.Translate.Async.Workers <= 0 {
+				cfg.Translate.Async.Workers = 1
+			}
+			for i := 0; i < cfg.Translate.Async.Workers; i++ {
+				go a.translateWorker(i)
+			}
 		}
 	}
 
 	// Initialize image proxy
 	if cfg.ImageProxy != nil {
-		a.ip = modules.NewImageProxy(cfg.ImageProxy, a.l)
+		a.ip, err = modules.NewImageProxy(cfg.ImageProxy, a.redis, cfg.System.Redis.Prefix, a.l)
+		if err != nil {
+			return fmt.Errorf("failed to initialize image proxy: %w", err)
+		}
 	}
 
 	// Initialize echo
@@ -95,12 +163,38 @@ func Start(cfg *types.Config) error {
 		return c.String(http.StatusOK, "RSSHub Smart Layer is running")
 	})
 
+	// Apply admin routes
+	if cfg.System.Admin != nil && cfg.System.Admin.Path != "" && cfg.System.Admin.Token != "" {
+		admin := a.e.Group(cfg.System.Admin.Path, middleware.KeyAuth(func(key string, c echo.Context) (bool, error) {
+			return subtle.ConstantTimeCompare([]byte(key), []byte(cfg.System.Admin.Token)) == 1, nil
+		}))
+
+		if a.tp != nil {
+			admin.GET("/translate/queue", a.adminTranslateQueue)
+			admin.GET("/translate/providers", a.adminTranslateProviders)
+			admin.GET("/translate/jobs", a.adminTranslateJobs)
+			admin.GET("/translate/job", a.adminTranslateJob)
+			admin.GET("/translate/usage", a.adminTranslateUsage)
+			admin.GET("/translate/overrides", a.adminTranslateOverrides)
+			admin.GET("/translate/override", a.adminTranslateOverrideGet)
+			admin.PUT("/translate/override", a.adminTranslateOverridePut)
+			admin.DELETE("/translate/override", a.adminTranslateOverrideDelete)
+		}
+	}
+
 	// Apply main route
 	a.e.GET("/:platform/*", a.process)
 
+	// Apply language prefixed route
+	if cfg.Translate != nil && cfg.Translate.PathPrefix != "" {
+		a.e.GET(cfg.Translate.PathPrefix+"/:lang/:platform/*", a.process)
+	}
+
 	// Apply image proxy route
 	if a.ip != nil {
-		a.e.GET(a.ip.Path(), a.ip.Proxy)
+		for _, path := range a.ip.Paths() {
+			a.e.GET(path, a.ip.Proxy)
+		}
 	}
 
 	return a.e.Start(cfg.System.Listen)
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/candinya/rsshub-smart-layer/modules/translate"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+	"golang.org/x/text/language"
+)
+
+const detectSampleLength = 500 // In runes
+
+// detectSampleLang: Detect source language with a sample of given texts, returns empty if unknown
+func (a *app) detectSampleLang(ctx context.Context, policy *translatePolicy, texts ...string) string {
+	// Build sample
+	plainTexts := make([]string, len(texts))
+	for i, text := range texts {
+		plainTexts[i] = plainText(text)
+	}
+	sample := strings.TrimSpace(strings.Join(plainTexts, "\n"))
+	if runes := []rune(sample); len(runes) > detectSampleLength {
+		sample = string(runes[:detectSampleLength])
+	}
+
+	if sample == "" {
+		return ""
+	}
+
+	return a.detectLang(ctx, policy, sample)
+}
+
+func (a *app) detectLang(ctx context.Context, policy *translatePolicy, text string) string {
+	// Local detectors always guess, so a minimum confidence is required
+	localConfidence := a.cfg.Translate.DetectConfidence
+	if localConfidence <= 0 {
+		localConfidence = translate.DetectMinConfidence
+	}
+
+	// Try with local script detector first, which is free
+	lang, confidence := translate.DetectScript(text)
+	a.l.Debug("script detect result", zap.String("lang", lang), zap.Float64("confidence", confidence))
+	if lang != "" && confidence >= localConfidence {
+		return lang
+	}
+
+	// Then local trigram detector for shared scripts
+	lang, confidence = translate.DetectNgram(text)
+	a.l.Debug("trigram detect result", zap.String("lang", lang), zap.Float64("confidence", confidence))
+	if lang != "" && confidence >= localConfidence {
+		return lang
+	}
+
+	// Build cache key
+	textHash := sha256.Sum256([]byte(text))
+	cacheKey := fmt.Sprintf("%s%s:%s:%s", a.cfg.System.Redis.Prefix, "detect", policy.provider, hex.EncodeToString(textHash[:]))
+
+	// Try to get from redis
+	cachedResult, err := a.redis.Get(ctx, cacheKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		a.l.Error("failed to check detected result from redis", zap.String("key", cacheKey), zap.Error(err))
+	} else if cachedResult != "" {
+		a.l.Debug("valid detected result found", zap.String("key", cacheKey), zap.String("result", cachedResult))
+		return cachedResult
+	}
+
+	// Send to provider
+	lang, confidence, err = a.translator(policy).Detect(ctx, text)
+	if err != nil {
+		if !errors.Is(err, translate.ErrDetectUnsupported) {
+			a.l.Error("failed to detect language", zap.Error(err))
+		}
+		return ""
+	}
+
+	a.l.Debug("provider detect result", zap.String("lang", lang), zap.Float64("confidence", confidence))
+	if confidence < a.cfg.Translate.DetectConfidence {
+		return ""
+	}
+
+	// Save into cache
+	a.redis.Set(context.Background(), cacheKey, lang, a.cfg.System.Redis.CacheExpire)
+
+	return lang
+}
+
+// isSameLang: Check if detected source language matches target language.
+// Only base language is compared if target has no script or region specified.
+func isSameLang(source string, target string) bool {
+	if strings.EqualFold(source, target) {
+		return true
+	}
+
+	sourceTag, err := language.Parse(source)
+	if err != nil {
+		return false
+	}
+	targetTag, err := language.Parse(target)
+	if err != nil {
+		return false
+	}
+
+	sourceBase, _ := sourceTag.Base()
+	targetBase, _ := targetTag.Base()
+
+	return targetBase.String() == targetTag.String() && sourceBase == targetBase
+}
+
+// plainText: Extract text from HTML
+func plainText(htmlText string) string {
+	var sb strings.Builder
+
+	z := html.NewTokenizer(strings.NewReader(htmlText))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return sb.String()
+		case html.TextToken:
+			sb.Write(z.Text())
+			sb.WriteByte(' ')
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"golang.org/x/text/language"
+)
+
+var langCodePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// targetLang: Select target language from (by priority) path prefix, query parameter, host and Accept-Language header
+func (a *app) targetLang(c echo.Context) *string {
+	req := c.Request()
+
+	// Path prefix
+	if lang := c.Param("lang"); lang != "" {
+		a.l.Debug("check path prefix language", zap.String("lang", lang))
+		if validated, ok := a.validateLang(lang); ok {
+			a.l.Info("translate is enabled by path prefix", zap.String("target", validated))
+			return &validated
+		}
+	}
+
+	// Query parameter
+	if a.cfg.Translate.QueryParam != "" {
+		if lang := c.QueryParam(a.cfg.Translate.QueryParam); lang != "" {
+			a.l.Debug("check query parameter language", zap.String("lang", lang))
+			if validated, ok := a.validateLang(lang); ok {
+				a.l.Info("translate is enabled by query parameter", zap.String("target", validated))
+				return &validated
+			}
+		}
+	}
+
+	// Host
+	a.l.Debug("check host language", zap.String("host", req.Host))
+	if a.cfg.Translate.HostBase != "" && strings.Contains(req.Host, a.cfg.Translate.HostBase) {
+		prefix := strings.SplitN(req.Host, a.cfg.Translate.HostBase, 2)
+		if validated, ok := a.validateLang(prefix[0]); ok {
+			a.l.Info("translate is enabled by host", zap.String("target", validated), zap.String("host", req.Host))
+			return &validated
+		}
+	}
+
+	// Accept-Language
+	if a.cfg.Translate.AcceptLanguage {
+		acceptLanguage := req.Header.Get("Accept-Language")
+		a.l.Debug("check Accept-Language header", zap.String("header", acceptLanguage))
+		tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+		if err != nil {
+			a.l.Debug("failed to parse Accept-Language header", zap.Error(err))
+			return nil
+		}
+
+		// Tags are sorted by quality
+		for _, tag := range tags {
+			if tag == language.Und {
+				continue
+			}
+
+			if validated, ok := a.validateLang(tag.String()); ok {
+				a.l.Info("translate is enabled by Accept-Language", zap.String("target", validated))
+				return &validated
+			}
+
+			// Try with base language
+			base, _ := tag.Base()
+			if validated, ok := a.validateLang(base.String()); ok {
+				a.l.Info("translate is enabled by Accept-Language", zap.String("target", validated))
+				return &validated
+			}
+		}
+	}
+
+	// Not found
+	return nil
+}
+
+// validateLang: Check language code against allowlist, or its format if no allowlist configured
+func (a *app) validateLang(lang string) (string, bool) {
+	if len(a.cfg.Translate.Languages) == 0 {
+		return lang, langCodePattern.MatchString(lang)
+	}
+
+	for _, allowed := range a.cfg.Translate.Languages {
+		if strings.EqualFold(lang, allowed) {
+			return allowed, true
+		}
+	}
+
+	a.l.Debug("language not allowed", zap.String("lang", lang))
+	return "", false
+}
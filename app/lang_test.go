@@ -0,0 +1,98 @@
+package app
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func TestValidateLang(t *testing.T) {
+	tests := []struct {
+		name      string
+		languages []string
+		lang      string
+		want      string
+		ok        bool
+	}{
+		{"format only", nil, "zh", "zh", true},
+		{"format with region", nil, "zh-Hans-CN", "zh-Hans-CN", true},
+		{"malformed", nil, "not a lang", "", false},
+		{"too short", nil, "z", "", false},
+		{"allowlist canonical case", []string{"zh-CN", "ja"}, "zh-cn", "zh-CN", true},
+		{"not in allowlist", []string{"zh-CN", "ja"}, "ko", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &app{
+				cfg: &types.Config{Translate: &types.ConfigTranslate{Languages: tt.languages}},
+				l:   zap.NewNop(),
+			}
+
+			got, ok := a.validateLang(tt.lang)
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Errorf("validateLang(%q) = %q, %v, want %q, %v", tt.lang, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTargetLang(t *testing.T) {
+	cfg := &types.ConfigTranslate{
+		HostBase:       ".rss.example.com",
+		QueryParam:     "lang",
+		PathPrefix:     "/_lang",
+		AcceptLanguage: true,
+		Languages:      []string{"zh", "ja", "en"},
+	}
+
+	tests := []struct {
+		name           string
+		pathLang       string
+		target         string
+		host           string
+		acceptLanguage string
+		want           string // Empty for no translation
+	}{
+		{"none", "", "/twitter/user/x", "rss.example.com", "", ""},
+		{"path prefix", "ja", "/_lang/ja/twitter/user/x?lang=zh", "zh.rss.example.com", "zh", "ja"},
+		{"query over host", "", "/twitter/user/x?lang=ja", "zh.rss.example.com", "", "ja"},
+		{"host", "", "/twitter/user/x", "zh.rss.example.com", "ja", "zh"},
+		{"invalid query falls through", "", "/twitter/user/x?lang=xx", "zh.rss.example.com", "", "zh"},
+		{"accept language by quality", "", "/twitter/user/x", "rss.example.com", "fr;q=0.9, ja;q=0.8, zh;q=0.5", "ja"},
+		{"accept language base", "", "/twitter/user/x", "rss.example.com", "zh-TW", "zh"},
+		{"accept language not allowed", "", "/twitter/user/x", "rss.example.com", "fr, de", ""},
+	}
+
+	e := echo.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &app{
+				cfg: &types.Config{Translate: cfg},
+				l:   zap.NewNop(),
+			}
+
+			req := httptest.NewRequest("GET", tt.target, nil)
+			req.Host = tt.host
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			c := e.NewContext(req, httptest.NewRecorder())
+			if tt.pathLang != "" {
+				c.SetParamNames("lang")
+				c.SetParamValues(tt.pathLang)
+			}
+
+			got := a.targetLang(c)
+			switch {
+			case tt.want == "" && got != nil:
+				t.Errorf("targetLang() = %q, want nil", *got)
+			case tt.want != "" && (got == nil || *got != tt.want):
+				t.Errorf("targetLang() = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"go.uber.org/zap"
+)
+
+// prewarmLoop: Periodically fetch and translate configured feeds to refresh cache before expire
+func (a *app) prewarmLoop() {
+	cfg := a.cfg.Translate.Prewarm
+
+	if cfg.Interval >= a.cfg.System.Redis.CacheExpire {
+		a.l.Warn("prewarm interval is not shorter than cache expire, cache might expire before refresh",
+			zap.Duration("interval", cfg.Interval),
+			zap.Duration("expire", a.cfg.System.Redis.CacheExpire),
+		)
+	}
+
+	// Start with a random delay to prevent flooding after restart
+	time.Sleep(a.prewarmJitter())
+
+	for {
+		a.prewarm()
+		time.Sleep(cfg.Interval + a.prewarmJitter())
+	}
+}
+
+func (a *app) prewarmJitter() time.Duration {
+	if a.cfg.Translate.Prewarm.Jitter <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(a.cfg.Translate.Prewarm.Jitter)))
+}
+
+func (a *app) prewarm() {
+	cfg := a.cfg.Translate.Prewarm
+	a.l.Info("start prewarm", zap.Int("feeds", len(cfg.Feeds)))
+	start := time.Now()
+
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+
+	var prewarmWg sync.WaitGroup
+	for _, feedCfg := range cfg.Feeds {
+		sem <- struct{}{}
+		prewarmWg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				prewarmWg.Done()
+			}()
+
+			a.prewarmFeed(feedCfg.Path, feedCfg.Languages)
+		}()
+	}
+
+	prewarmWg.Wait()
+	a.l.Info("prewarm finished", zap.Duration("duration", time.Since(start)))
+}
+
+func (a *app) prewarmFeed(path string, languages []string) {
+	// Get platform from path
+	platform, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+
+	// Fetch feed
+	a.l.Debug("prewarm feed", zap.String("path", path), zap.String("platform", platform))
+	feed, err := a.lb.Fetch(path, platform)
+	if err != nil {
+		a.l.Warn("failed to fetch feed for prewarm", zap.String("path", path), zap.Error(err))
+		return
+	}
+
+	// Translate into each language
+	for _, lang := range languages {
+		targetLang, ok := a.validateLang(lang)
+		if !ok {
+			a.l.Warn("invalid prewarm language", zap.String("path", path), zap.String("lang", lang))
+			continue
+		}
+
+		// Work on a copy, as translation modifies feed in place
+		policy := a.matchTranslatePolicy(platform, path)
+		policy.prewarm = true
+		a.translateFeed(context.Background(), copyFeed(feed), &targetLang, policy)
+	}
+}
+
+// copyFeed: Copy all parts of feed which might be modified by translation
+func copyFeed(feed *feeds.Feed) *feeds.Feed {
+	feedCopy := *feed
+	if feed.Author != nil {
+		author := *feed.Author
+		feedCopy.Author = &author
+	}
+	if feed.Image != nil {
+		image := *feed.Image
+		feedCopy.Image = &image
+	}
+
+	feedCopy.Items = make([]*feeds.Item, len(feed.Items))
+	for i, item := range feed.Items {
+		itemCopy := *item
+		if item.Author != nil {
+			author := *item.Author
+			itemCopy.Author = &author
+		}
+		feedCopy.Items[i] = &itemCopy
+	}
+
+	return &feedCopy
+}
@@ -19,8 +19,14 @@ func (a *app) process(c echo.Context) error {
 
 	a.l.Debug("platform", zap.String("platform", platform))
 
+	// Get feed path, strip language prefix if exists
+	path := req.URL.Path
+	if lang := c.Param("lang"); lang != "" {
+		path = strings.TrimPrefix(path, a.cfg.Translate.PathPrefix+"/"+lang)
+	}
+
 	// Get data from load balancer
-	feed, err := a.lb.Fetch(req.URL.Path, platform)
+	feed, err := a.lb.Fetch(path, platform)
 	if err != nil {
 		a.l.Error("failed to fetch feed", zap.Error(err))
 		return c.NoContent(http.StatusServiceUnavailable)
@@ -30,34 +36,22 @@ func (a *app) process(c echo.Context) error {
 
 	// Check if translate is enabled
 	var targetLang *string = nil
-	a.l.Debug("check if translate is enabled", zap.String("host", req.Host))
-	if a.cfg.Translate != nil && a.cfg.Translate.HostBase != "" &&
-		strings.Contains(req.Host, a.cfg.Translate.HostBase) {
-		// Found
-		prefix := strings.SplitN(req.Host, a.cfg.Translate.HostBase, 2)
-		targetLang = &prefix[0]
-
-		a.l.Info("translate is enabled", zap.String("target", *targetLang), zap.String("host", req.Host))
+	if a.cfg.Translate != nil {
+		targetLang = a.targetLang(c)
 	}
 
 	a.l.Debug("start translate & image proxy")
 
-	// Translate & Image proxy
-	if a.tp != nil && targetLang != nil || a.ip != nil {
-		for i, item := range feed.Items {
-			processedItem := item
-
-			// Translate
-			if a.tp != nil && targetLang != nil {
-				processedItem = a.translateItem(processedItem, targetLang, platform)
-			}
-
-			// Image proxy
-			if a.ip != nil {
-				processedItem = a.imageProxyItem(processedItem, req.Host, platform)
-			}
+	// Translate
+	if a.tp != nil && targetLang != nil {
+		a.translateFeed(req.Context(), feed, targetLang, a.matchTranslatePolicy(platform, path))
+	}
 
-			feed.Items[i] = processedItem
+	// Image proxy
+	if a.ip != nil {
+		base := a.ip.BaseURL(req)
+		for i, item := range feed.Items {
+			feed.Items[i] = a.imageProxyItem(item, base, platform)
 		}
 	}
 
@@ -2,109 +2,354 @@ package app
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
+	"unicode/utf8"
 
+	"github.com/candinya/rsshub-smart-layer/modules"
+	"github.com/candinya/rsshub-smart-layer/modules/translate"
 	"github.com/gorilla/feeds"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
-func (a *app) translateItem(item *feeds.Item, targetLang *string, platform string) *feeds.Item {
-	// Translate wg
-	var translateWg sync.WaitGroup
-
-	// Translate channels
-	tTitle := make(chan *string, 1)
-	tDescription := make(chan *string, 1)
-	tContent := make(chan *string, 1)
+// translateField describes a part to translate and how to apply the translated result
+type translateField struct {
+	part   string
+	src    string
+	isHTML bool
+	apply  func(translated string)
+}
 
-	// Title
-	if item.Title != "" {
-		a.l.Debug("translate item title", zap.String("title", item.Title))
-		translateWg.Add(1)
-		go func() {
-			defer translateWg.Done()
-			tTitle <- a.translatePart(item.Title, *targetLang, false, platform, item.Id, "title")
-		}()
+func (a *app) translateFeed(ctx context.Context, feed *feeds.Feed, targetLang *string, policy *translatePolicy) {
+	// Apply translation deadline, untranslated parts are kept as-is after that
+	if a.cfg.Translate.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.cfg.Translate.Deadline)
+		defer cancel()
 	}
 
-	// Description
-	if item.Description != "" {
-		a.l.Debug("translate item description", zap.String("description", item.Description))
-		translateWg.Add(1)
-		go func() {
-			defer translateWg.Done()
-			tDescription <- a.translatePart(item.Description, *targetLang, true, platform, item.Id, "description")
-		}()
-	}
+	var translateWg sync.WaitGroup
+
+	// Translate feed metadata
+	translateWg.Add(1)
+	go func() {
+		defer translateWg.Done()
+		a.translateFeedMeta(ctx, feed, targetLang, policy)
+	}()
+
+	// Translate all items simultaneously, concurrency is limited by provider
+	for i, item := range feed.Items {
+		if policy.maxItems > 0 && i >= policy.maxItems {
+			break
+		}
 
-	// Content
-	if item.Content != "" {
-		a.l.Debug("translate item content", zap.String("content", item.Content))
 		translateWg.Add(1)
 		go func() {
 			defer translateWg.Done()
-			tContent <- a.translatePart(item.Content, *targetLang, true, platform, item.Id, "content")
+			feed.Items[i] = a.translateItem(ctx, item, targetLang, policy)
 		}()
 	}
 
 	// Wait for all finish
 	translateWg.Wait()
+}
+
+func (a *app) translateFeedMeta(ctx context.Context, feed *feeds.Feed, targetLang *string, policy *translatePolicy) {
+	// Prepare fields
+	fields := []translateField{
+		{"feed_title", feed.Title, false, func(t string) { feed.Title = t }},
+		{"feed_description", feed.Description, false, func(t string) { feed.Description = t }},
+	}
+	if feed.Author != nil {
+		fields = append(fields, translateField{"feed_author", feed.Author.Name, false, func(t string) { feed.Author.Name = t }})
+	}
 
-	// Close channels
-	close(tTitle)
-	close(tDescription)
-	close(tContent)
+	// Use feed link as id
+	id := ""
+	if feed.Link != nil {
+		id = feed.Link.Href
+	}
 
-	// Check channel results
-	translatedTitle := <-tTitle
-	if translatedTitle != nil {
-		a.l.Debug("item title translated", zap.String("title", item.Title), zap.String("translated", *translatedTitle))
-		item.Title = *translatedTitle
+	// Detect source language
+	sourceLang := policy.sourceLang
+	if sourceLang == "" && a.cfg.Translate.DetectSource {
+		sourceLang = a.detectSampleLang(ctx, policy, feed.Title, feed.Description)
+	}
+	if sourceLang != "" && isSameLang(sourceLang, *targetLang) {
+		a.l.Debug("feed is already in target language", zap.String("id", id), zap.String("lang", sourceLang))
+		return
 	}
 
-	translatedDescription := <-tDescription
-	if translatedDescription != nil {
-		a.l.Debug("item description translated", zap.String("description", item.Description), zap.String("translated", *translatedDescription))
-		item.Description = *translatedDescription
+	a.translateFields(ctx, fields, sourceLang, *targetLang, policy, id)
+}
+
+func (a *app) translateItem(ctx context.Context, item *feeds.Item, targetLang *string, policy *translatePolicy) *feeds.Item {
+	// Prepare fields
+	fields := []translateField{
+		{"title", item.Title, false, func(t string) { item.Title = t }},
+		{"description", item.Description, true, func(t string) { item.Description = t }},
+		{"content", item.Content, true, func(t string) { item.Content = t }},
+	}
+	if item.Author != nil {
+		fields = append(fields, translateField{"author", item.Author.Name, false, func(t string) { item.Author.Name = t }})
 	}
 
-	translatedContent := <-tContent
-	if translatedContent != nil {
-		a.l.Debug("item content translated", zap.String("content", item.Content), zap.String("translated", *translatedContent))
-		item.Content = *translatedContent
+	// Detect source language
+	sourceLang := policy.sourceLang
+	if sourceLang == "" && a.cfg.Translate.DetectSource {
+		sourceLang = a.detectSampleLang(ctx, policy, item.Title, item.Description, item.Content)
 	}
+	if sourceLang != "" && isSameLang(sourceLang, *targetLang) {
+		a.l.Debug("item is already in target language", zap.String("id", item.Id), zap.String("lang", sourceLang))
+		return item
+	}
+
+	a.translateFields(ctx, fields, sourceLang, *targetLang, policy, item.Id)
 
 	return item
 }
 
-func (a *app) translatePart(src string, targetLang string, isHTML bool, platform string, id string, part string) *string {
-	// Build cache key
-	cacheKey := fmt.Sprintf("%s%s:%s:%s:%s:%s", a.cfg.System.Redis.Prefix, "translate", platform, id, part, targetLang)
+// translateFields: Translate all enabled and non-empty fields simultaneously
+func (a *app) translateFields(ctx context.Context, fields []translateField, sourceLang string, targetLang string, policy *translatePolicy, id string) {
+	// Translate wg
+	var translateWg sync.WaitGroup
+
+	for _, field := range fields {
+		if field.src == "" || !policy.fieldEnabled(field.part) {
+			continue
+		}
+
+		a.l.Debug("translate part", zap.String("part", field.part), zap.String("id", id), zap.String("src", field.src))
+		translateWg.Add(1)
+		go func() {
+			defer translateWg.Done()
+			translated := a.translatePart(ctx, field.src, sourceLang, targetLang, field.isHTML, policy, id, field.part)
+			if translated != nil {
+				a.l.Debug("part translated", zap.String("part", field.part), zap.String("id", id), zap.String("src", field.src), zap.String("translated", *translated))
+				field.apply(*translated)
+			}
+		}()
+	}
+
+	// Wait for all finish
+	translateWg.Wait()
+}
+
+func (a *app) translatePart(ctx context.Context, src string, sourceLang string, targetLang string, isHTML bool, policy *translatePolicy, id string, part string) *string {
+	// Manual overrides take precedence
+	if id != "" {
+		override, err := a.getTranslateOverride(ctx, policy.platform, id, part, targetLang)
+		if err != nil {
+			a.l.Error("failed to check translate override", zap.String("part", part), zap.String("id", id), zap.Error(err))
+		} else if override != nil {
+			a.l.Debug("translate override found", zap.String("part", part), zap.String("id", id), zap.String("text", override.Text))
+			return &override.Text
+		}
+	}
+
+	// Build cache key by content, so identical text across feeds is translated once
+	cacheKey := a.translateCacheKey(src, policy.provider, targetLang, isHTML, a.masker(policy).Fingerprint(targetLang))
 
 	// Try to get from redis
 	a.l.Debug("try to get cache", zap.String("key", cacheKey))
-	cachedResult, err := a.redis.Get(context.Background(), cacheKey).Result()
-	if err != nil {
+	cachedResult, err := a.redis.Get(ctx, cacheKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
 		a.l.Error("failed to check translated result from redis", zap.String("key", cacheKey), zap.Error(err))
 	} else if cachedResult != "" {
 		// Valid cache result, return
 		a.l.Debug("valid translated result found", zap.String("key", cacheKey), zap.String("result", cachedResult))
+		if policy.prewarm {
+			// Refresh before expire, unless it's a suspicious result which should expire shortly
+			suspicious, err := a.redis.Exists(ctx, a.translateSuspiciousKey(cacheKey)).Result()
+			if err != nil {
+				a.l.Error("failed to check suspicious mark from redis", zap.String("key", cacheKey), zap.Error(err))
+			} else if suspicious == 0 {
+				a.redis.Expire(context.Background(), cacheKey, a.cfg.System.Redis.CacheExpire)
+			}
+		}
+		a.indexTranslation(cacheKey, targetLang, policy.platform, id, part)
 		return &cachedResult
 	}
 
-	// Send to translate provider
-	a.l.Debug("try to send with provider")
-	translatedPart, err := a.tp.Translate(src, targetLang, isHTML)
-	if err != nil {
-		a.l.Error("failed to translate", zap.String("part", part), zap.String("id", id), zap.Error(err))
+	// Leave to background workers in async mode, keep untranslated for now.
+	// Prewarm is already running in background, so always translate directly.
+	if a.cfg.Translate.Async.Enable && !policy.prewarm {
+		a.enqueueTranslation(ctx, cacheKey, &translateJob{
+			Src:        src,
+			SourceLang: sourceLang,
+			TargetLang: targetLang,
+			IsHTML:     isHTML,
+			Rule:       policy.rule,
+			Platform:   policy.platform,
+			ID:         id,
+			Part:       part,
+		})
 		return nil
 	}
 
-	// Save into cache
-	a.l.Debug("save translated result into cache", zap.String("part", part), zap.String("id", id), zap.String("source", src), zap.String("translated", *translatedPart))
-	a.redis.Set(context.Background(), cacheKey, translatedPart, a.cfg.System.Redis.CacheExpire)
+	return a.translateSource(ctx, cacheKey, src, sourceLang, targetLang, isHTML, policy, id, part)
+}
+
+// translateSource: Translate with provider (and fallbacks) and save into cache
+func (a *app) translateSource(ctx context.Context, cacheKey string, src string, sourceLang string, targetLang string, isHTML bool, policy *translatePolicy, id string, part string) *string {
+	var suspicious *string
+
+	for _, provider := range a.providerChain(policy) {
+		// Use current provider
+		providerPolicy := *policy
+		providerPolicy.provider = provider
+
+		// Check budget
+		switch a.um.Check(ctx, provider) {
+		case modules.BudgetHard:
+			a.l.Warn("hard translate budget exceeded, skip", zap.String("provider", provider), zap.String("part", part), zap.String("id", id))
+			continue
+		case modules.BudgetSoft:
+			if isHTML {
+				a.l.Debug("soft translate budget exceeded, skip html part", zap.String("provider", provider), zap.String("part", part), zap.String("id", id))
+				continue
+			}
+		}
+
+		var (
+			translatedPart *string
+			sent           bool
+		)
+		if isHTML && a.cfg.Translate.Segment.Enable {
+			// Translate text segments of HTML
+			translatedPart, sent = a.translateSegments(ctx, src, sourceLang, targetLang, &providerPolicy, id, part)
+		} else {
+			// Translate as a whole
+			translatedPart, sent = a.translateText(ctx, src, sourceLang, targetLang, isHTML, &providerPolicy, id, part)
+		}
+
+		if translatedPart == nil {
+			// Try with next provider
+			continue
+		}
+
+		// Validate result, unless nothing is sent to provider
+		if sent {
+			err := a.tv.Validate(src, *translatedPart, isHTML)
+			if err != nil {
+				a.l.Warn("suspicious translated result", zap.String("provider", provider), zap.String("part", part), zap.String("id", id), zap.String("translated", *translatedPart), zap.Error(err))
+				if suspicious == nil {
+					suspicious = translatedPart
+				}
+				continue
+			}
+		}
+
+		// Save into cache
+		a.l.Debug("save translated result into cache", zap.String("provider", provider), zap.String("part", part), zap.String("id", id), zap.String("source", src), zap.String("translated", *translatedPart))
+		a.redis.Set(context.Background(), cacheKey, translatedPart, a.cfg.System.Redis.CacheExpire)
+		if a.cfg.Translate.Validate.SuspiciousTTL > 0 {
+			a.redis.Del(context.Background(), a.translateSuspiciousKey(cacheKey))
+		}
+		a.indexTranslation(cacheKey, targetLang, policy.platform, id, part)
+
+		// Return
+		return translatedPart
+	}
+
+	// All providers failed, use suspicious result only if it's allowed to be cached shortly
+	if suspicious != nil && a.cfg.Translate.Validate.SuspiciousTTL > 0 {
+		a.l.Debug("save suspicious result into cache", zap.String("part", part), zap.String("id", id), zap.String("translated", *suspicious))
+		a.redis.Set(context.Background(), cacheKey, suspicious, a.cfg.Translate.Validate.SuspiciousTTL)
+		a.redis.Set(context.Background(), a.translateSuspiciousKey(cacheKey), 1, a.cfg.Translate.Validate.SuspiciousTTL)
+		return suspicious
+	}
+
+	return nil
+}
+
+// providerChain: Policy provider first, then fallbacks
+func (a *app) providerChain(policy *translatePolicy) []string {
+	chain := []string{policy.provider}
+	for _, provider := range a.cfg.Translate.Fallbacks {
+		if !slices.Contains(chain, provider) {
+			chain = append(chain, provider)
+		}
+	}
+
+	return chain
+}
+
+// translateText: Translate as a whole, also returns whether it's sent to provider
+func (a *app) translateText(ctx context.Context, src string, sourceLang string, targetLang string, isHTML bool, policy *translatePolicy, id string, part string) (*string, bool) {
+	// Mask protected parts and glossary terms
+	masked, replacements := a.masker(policy).Mask(src, targetLang, isHTML)
+	a.l.Debug("masked source", zap.String("masked", masked), zap.Strings("replacements", replacements))
+
+	var (
+		translatedPart *string
+		err            error
+		sent           bool
+	)
+	if translate.OnlyPlaceholders(masked) {
+		// Nothing to translate
+		translatedPart = &masked
+	} else {
+		// Send to translate provider
+		a.l.Debug("try to send with provider")
+		translatedPart, err = a.translator(policy).Translate(ctx, masked, sourceLang, targetLang, isHTML)
+		if errors.Is(err, translate.ErrNoTranslation) {
+			a.l.Debug("no translation from provider", zap.String("provider", policy.provider), zap.String("part", part), zap.String("id", id))
+			return nil, false
+		} else if err != nil {
+			a.l.Error("failed to translate", zap.String("provider", policy.provider), zap.String("part", part), zap.String("id", id), zap.Error(err))
+			return nil, false
+		}
+		sent = true
+		a.um.Record(context.Background(), policy.provider, policy.platform, targetLang, int64(utf8.RuneCountInString(masked)))
+	}
+
+	// Restore masked parts
+	unmasked, ok := translate.Unmask(*translatedPart, replacements)
+	if !ok {
+		a.l.Warn("placeholders lost in translation", zap.String("part", part), zap.String("id", id), zap.String("translated", *translatedPart))
+		return nil, false
+	}
+
+	return &unmasked, sent
+}
+
+// translateCacheKey: Build cache key from provider, target language, format and hash of source text
+// together with masking rules (glossary and protected patterns)
+func (a *app) translateCacheKey(src string, provider string, targetLang string, isHTML bool, maskFingerprint string) string {
+	format := "text"
+	if isHTML {
+		format = "html"
+	}
+
+	srcHash := sha256.Sum256([]byte(src))
+	if maskFingerprint != "" {
+		srcHash = sha256.Sum256([]byte(maskFingerprint + "\x00" + src))
+	}
+
+	return fmt.Sprintf("%s%s:%s:%s:%s:%s", a.cfg.System.Redis.Prefix, "translate", provider, targetLang, format, hex.EncodeToString(srcHash[:]))
+}
+
+// translateSuspiciousKey: Mark of cached result which failed validation, which is never refreshed
+func (a *app) translateSuspiciousKey(cacheKey string) string {
+	return cacheKey + ":suspicious"
+}
+
+// translateIndexKey: Build secondary index key from item info, which points to the content cache key
+func (a *app) translateIndexKey(targetLang string, platform string, id string, part string) string {
+	return fmt.Sprintf("%s%s:%s:%s:%s:%s", a.cfg.System.Redis.Prefix, "translate-index", platform, id, part, targetLang)
+}
+
+func (a *app) indexTranslation(cacheKey string, targetLang string, platform string, id string, part string) {
+	// Items without id can not be indexed
+	if id == "" {
+		return
+	}
 
-	// Return
-	return translatedPart
+	a.redis.Set(context.Background(), a.translateIndexKey(targetLang, platform, id, part), cacheKey, a.cfg.System.Redis.CacheExpire)
 }
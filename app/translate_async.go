@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	translateJobQueued  = "queued"
+	translateJobRunning = "running"
+	translateJobDone    = "done"
+	translateJobFailed  = "failed"
+)
+
+const (
+	defaultTranslateJobExpire = 10 * time.Minute
+	translateWorkerPoll       = 5 * time.Second
+)
+
+type translateJob struct {
+	Src        string `json:"src"`
+	SourceLang string `json:"source_lang"`
+	TargetLang string `json:"target_lang"`
+	IsHTML     bool   `json:"is_html"`
+	Rule       string `json:"rule"`
+	Platform   string `json:"platform"`
+	ID         string `json:"id"`
+	Part       string `json:"part"`
+	CacheKey   string `json:"cache_key"`
+}
+
+type translateJobStats struct {
+	Depth   int64            `json:"depth"`
+	Workers int              `json:"workers"`
+	Counts  map[string]int64 `json:"counts"`
+}
+
+func (a *app) translateQueueKey() string {
+	return fmt.Sprintf("%s%s", a.cfg.System.Redis.Prefix, "translate-queue")
+}
+
+func (a *app) translateJobStatsKey() string {
+	return fmt.Sprintf("%s%s", a.cfg.System.Redis.Prefix, "translate-jobs")
+}
+
+func (a *app) translateJobKey(cacheKey string) string {
+	return cacheKey + ":job"
+}
+
+func (a *app) translateJobExpire() time.Duration {
+	if a.cfg.Translate.Async.JobExpire > 0 {
+		return a.cfg.Translate.Async.JobExpire
+	}
+
+	return defaultTranslateJobExpire
+}
+
+// enqueueTranslation: Push job into queue, unless same job is already queued or running
+func (a *app) enqueueTranslation(ctx context.Context, cacheKey string, job *translateJob) {
+	job.CacheKey = cacheKey
+
+	// Deduplicate by job status
+	jobKey := a.translateJobKey(cacheKey)
+	created, err := a.redis.SetNX(ctx, jobKey, translateJobQueued, a.translateJobExpire()).Result()
+	if err != nil {
+		a.l.Error("failed to set translate job status", zap.String("key", jobKey), zap.Error(err))
+		return
+	} else if !created {
+		a.l.Debug("translate job already exists", zap.String("key", jobKey))
+		return
+	}
+
+	// Push into queue
+	jobBytes, err := json.Marshal(job)
+	if err != nil {
+		a.l.Error("failed to marshal translate job", zap.Error(err))
+		return
+	}
+
+	err = a.redis.LPush(ctx, a.translateQueueKey(), jobBytes).Err()
+	if err != nil {
+		a.l.Error("failed to enqueue translate job", zap.String("key", jobKey), zap.Error(err))
+		a.redis.Del(context.Background(), jobKey) // Allow retry with next request
+		return
+	}
+
+	a.redis.HIncrBy(context.Background(), a.translateJobStatsKey(), translateJobQueued, 1)
+	a.l.Debug("translate job queued", zap.String("key", jobKey))
+}
+
+// translateWorker: Process translate jobs in background
+func (a *app) translateWorker(no int) {
+	a.l.Info("translate worker started", zap.Int("no", no))
+
+	for {
+		// Wait for job
+		res, err := a.redis.BRPop(context.Background(), translateWorkerPoll, a.translateQueueKey()).Result()
+		if err != nil {
+			if !errors.Is(err, redis.Nil) {
+				a.l.Error("failed to get translate job", zap.Int("worker", no), zap.Error(err))
+				time.Sleep(translateWorkerPoll) // Prevent busy loop when redis is down
+			}
+			continue
+		}
+
+		// Result is [key, value]
+		var job translateJob
+		err = json.Unmarshal([]byte(res[1]), &job)
+		if err != nil {
+			a.l.Error("failed to unmarshal translate job", zap.String("job", res[1]), zap.Error(err))
+			continue
+		}
+
+		a.processTranslateJob(&job)
+	}
+}
+
+func (a *app) processTranslateJob(job *translateJob) {
+	jobKey := a.translateJobKey(job.CacheKey)
+	a.l.Debug("process translate job", zap.String("key", jobKey))
+	a.redis.Set(context.Background(), jobKey, translateJobRunning, a.translateJobExpire())
+
+	// Apply translation deadline
+	ctx := context.Background()
+	if a.cfg.Translate.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.cfg.Translate.Deadline)
+		defer cancel()
+	}
+
+	status := translateJobDone
+	if a.translateSource(ctx, job.CacheKey, job.Src, job.SourceLang, job.TargetLang, job.IsHTML, a.newTranslatePolicy(job.Rule, job.Platform), job.ID, job.Part) == nil {
+		status = translateJobFailed
+	}
+
+	a.redis.Set(context.Background(), jobKey, status, a.translateJobExpire())
+	a.redis.HIncrBy(context.Background(), a.translateJobStatsKey(), status, 1)
+	a.l.Debug("translate job finished", zap.String("key", jobKey), zap.String("status", status))
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type translateOverride struct {
+	Platform string    `json:"platform"`
+	ID       string    `json:"id"`
+	Part     string    `json:"part"`
+	Lang     string    `json:"lang"`
+	Text     string    `json:"text"`
+	Updated  time.Time `json:"updated"`
+}
+
+// translateOverridesKey: Overrides are kept in a hash without expire, so they are never evicted by cache expire
+func (a *app) translateOverridesKey() string {
+	return fmt.Sprintf("%s%s", a.cfg.System.Redis.Prefix, "translate-overrides")
+}
+
+func translateOverrideField(platform string, id string, part string, lang string) string {
+	return fmt.Sprintf("%s:%s:%s:%s", platform, part, lang, id)
+}
+
+// getTranslateOverride: Returns nil if not found
+func (a *app) getTranslateOverride(ctx context.Context, platform string, id string, part string, lang string) (*translateOverride, error) {
+	value, err := a.redis.HGet(ctx, a.translateOverridesKey(), translateOverrideField(platform, id, part, lang)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get override: %w", err)
+	}
+
+	var override translateOverride
+	err = json.Unmarshal([]byte(value), &override)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal override: %w", err)
+	}
+
+	return &override, nil
+}
+
+func (a *app) setTranslateOverride(ctx context.Context, override *translateOverride) error {
+	override.Updated = time.Now()
+	value, err := json.Marshal(override)
+	if err != nil {
+		return fmt.Errorf("failed to marshal override: %w", err)
+	}
+
+	return a.redis.HSet(ctx, a.translateOverridesKey(), translateOverrideField(override.Platform, override.ID, override.Part, override.Lang), value).Err()
+}
+
+func (a *app) deleteTranslateOverride(ctx context.Context, platform string, id string, part string, lang string) (bool, error) {
+	deleted, err := a.redis.HDel(ctx, a.translateOverridesKey(), translateOverrideField(platform, id, part, lang)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete override: %w", err)
+	}
+
+	return deleted > 0, nil
+}
+
+func (a *app) listTranslateOverrides(ctx context.Context) ([]translateOverride, error) {
+	values, err := a.redis.HVals(ctx, a.translateOverridesKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list overrides: %w", err)
+	}
+
+	overrides := make([]translateOverride, 0, len(values))
+	for _, value := range values {
+		var override translateOverride
+		err = json.Unmarshal([]byte(value), &override)
+		if err != nil {
+			a.l.Warn("failed to unmarshal override", zap.String("value", value), zap.Error(err))
+			continue
+		}
+		overrides = append(overrides, override)
+	}
+
+	return overrides, nil
+}
+
+// getIndexedTranslation: Find cached translation of item part through secondary index
+func (a *app) getIndexedTranslation(ctx context.Context, platform string, id string, part string, lang string) (string, string, error) {
+	cacheKey, err := a.redis.Get(ctx, a.translateIndexKey(lang, platform, id, part)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", "", nil
+	} else if err != nil {
+		return "", "", fmt.Errorf("failed to get index: %w", err)
+	}
+
+	cached, err := a.redis.Get(ctx, cacheKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return cacheKey, "", nil
+	} else if err != nil {
+		return cacheKey, "", fmt.Errorf("failed to get cache: %w", err)
+	}
+
+	return cacheKey, cached, nil
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/candinya/rsshub-smart-layer/modules/translate"
+	"github.com/candinya/rsshub-smart-layer/types"
+)
+
+// Item fields are translated by default, feed fields and authors are optional
+var defaultTranslateFields = []string{"title", "description", "content"}
+
+// translatePolicy holds translate settings resolved for a feed request
+type translatePolicy struct {
+	rule       string // Matched rule key, empty for default
+	platform   string
+	provider   string
+	sourceLang string // Forced source language, empty to detect or auto
+	fields     []string
+	maxItems   int  // 0 for unlimited
+	prewarm    bool // Refresh cache entries and skip async queue
+}
+
+// matchTranslatePolicy: Find rule by feed path prefix (longest first), then by platform name
+func (a *app) matchTranslatePolicy(platform string, path string) *translatePolicy {
+	matched := ""
+	for key := range a.cfg.Translate.Rules {
+		if strings.HasPrefix(key, "/") && strings.HasPrefix(path, key) && len(key) > len(matched) {
+			matched = key
+		}
+	}
+
+	if matched == "" {
+		if _, ok := a.cfg.Translate.Rules[platform]; ok {
+			matched = platform
+		}
+	}
+
+	return a.newTranslatePolicy(matched, platform)
+}
+
+// newTranslatePolicy: Build policy with rule, fallback to global settings
+func (a *app) newTranslatePolicy(ruleKey string, platform string) *translatePolicy {
+	policy := &translatePolicy{
+		rule:     ruleKey,
+		platform: platform,
+		provider: a.cfg.Translate.Provider,
+		fields:   a.cfg.Translate.Fields,
+	}
+
+	if rule, ok := a.cfg.Translate.Rules[ruleKey]; ok {
+		if rule.Provider != "" {
+			policy.provider = rule.Provider
+		}
+		if len(rule.Fields) > 0 {
+			policy.fields = rule.Fields
+		}
+		policy.sourceLang = rule.SourceLang
+		policy.maxItems = rule.MaxItems
+	}
+
+	if len(policy.fields) == 0 {
+		policy.fields = defaultTranslateFields
+	}
+
+	return policy
+}
+
+func (p *translatePolicy) fieldEnabled(part string) bool {
+	return slices.Contains(p.fields, part)
+}
+
+func (a *app) translator(policy *translatePolicy) *translate.Limited {
+	return a.tp[policy.provider]
+}
+
+func (a *app) masker(policy *translatePolicy) *translate.Masker {
+	if m, ok := a.tm[policy.rule]; ok {
+		return m
+	}
+
+	return a.tm[""]
+}
+
+// newTranslateMaskers: Build default masker and per rule maskers (which include default rules)
+func newTranslateMaskers(cfg *types.ConfigTranslate) (map[string]*translate.Masker, error) {
+	maskers := make(map[string]*translate.Masker)
+
+	var err error
+	maskers[""], err = translate.NewMasker(cfg.Protect, cfg.Glossary)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, rule := range cfg.Rules {
+		// Merge protect patterns
+		protect := append(slices.Clone(cfg.Protect), rule.Protect...)
+
+		// Merge glossary, rule terms take precedence
+		glossary := make(map[string]map[string]string)
+		for _, g := range []map[string]map[string]string{cfg.Glossary, rule.Glossary} {
+			for lang, terms := range g {
+				if _, ok := glossary[lang]; !ok {
+					glossary[lang] = make(map[string]string)
+				}
+				maps.Copy(glossary[lang], terms)
+			}
+		}
+
+		maskers[key], err = translate.NewMasker(protect, glossary)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", key, err)
+		}
+	}
+
+	return maskers, nil
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/candinya/rsshub-smart-layer/modules/translate"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSegmentMaxChars  = 2000
+	defaultSegmentBatchSize = 50
+)
+
+// segmentPiece is a piece of masked text segment to send to provider
+type segmentPiece struct {
+	segment int
+	text    string
+	keep    bool // Nothing to translate
+}
+
+// translateSegments: Translate text nodes of HTML as plain text in batches, then reassemble the DOM.
+// Also returns whether any segment is sent to provider.
+func (a *app) translateSegments(ctx context.Context, src string, sourceLang string, targetLang string, policy *translatePolicy, id string, part string) (*string, bool) {
+	maxChars := a.cfg.Translate.Segment.MaxChars
+	if maxChars <= 0 {
+		maxChars = defaultSegmentMaxChars
+	}
+	batchSize := a.cfg.Translate.Segment.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultSegmentBatchSize
+	}
+
+	// Extract text segments
+	segments, err := translate.SegmentHTML(src)
+	if err != nil {
+		a.l.Error("failed to segment html", zap.String("part", part), zap.String("id", id), zap.Error(err))
+		return nil, false
+	}
+
+	texts := segments.Texts()
+	a.l.Debug("html segmented", zap.String("part", part), zap.String("id", id), zap.Int("segments", len(texts)))
+
+	// Mask segments and split into pieces for provider length limit
+	replacements := make([][]string, len(texts))
+	var pieces []segmentPiece
+	for i, text := range texts {
+		var masked string
+		masked, replacements[i] = a.masker(policy).Mask(text, targetLang, false)
+		if translate.OnlyPlaceholders(masked) {
+			// Nothing to translate, keep as-is
+			pieces = append(pieces, segmentPiece{i, masked, true})
+			continue
+		}
+
+		for _, piece := range translate.SplitText(masked, maxChars) {
+			pieces = append(pieces, segmentPiece{i, piece, false})
+		}
+	}
+
+	// Translate pieces in batches
+	translatedPieces := make([]string, len(pieces))
+	var todo []int
+	for i, piece := range pieces {
+		if piece.keep {
+			translatedPieces[i] = piece.text
+		} else {
+			todo = append(todo, i)
+		}
+	}
+
+	sent := len(todo) > 0
+	for len(todo) > 0 {
+		// Collect batch within limits
+		var (
+			batch []string
+			chars int
+			size  int
+		)
+		for size < len(todo) && size < batchSize {
+			pieceChars := utf8.RuneCountInString(pieces[todo[size]].text)
+			if size > 0 && chars+pieceChars > maxChars {
+				break
+			}
+			batch = append(batch, pieces[todo[size]].text)
+			chars += pieceChars
+			size++
+		}
+
+		a.l.Debug("translate segment batch", zap.String("part", part), zap.String("id", id), zap.Int("size", size), zap.Int("chars", chars))
+		translatedBatch, err := a.translator(policy).TranslateBatch(ctx, batch, sourceLang, targetLang, false)
+		if err != nil {
+			a.l.Error("failed to translate segments", zap.String("provider", policy.provider), zap.String("part", part), zap.String("id", id), zap.Error(err))
+			return nil, false
+		}
+		a.um.Record(context.Background(), policy.provider, policy.platform, targetLang, int64(chars))
+		for i, translated := range translatedBatch {
+			translatedPieces[todo[i]] = translated
+		}
+
+		todo = todo[size:]
+	}
+
+	// Join pieces back into segments
+	joined := make([]strings.Builder, len(texts))
+	for i, piece := range pieces {
+		joined[piece.segment].WriteString(translatedPieces[i])
+	}
+
+	// Restore masked parts
+	translatedTexts := make([]string, len(texts))
+	for i := range texts {
+		unmasked, ok := translate.Unmask(joined[i].String(), replacements[i])
+		if !ok {
+			a.l.Warn("placeholders lost in translation", zap.String("part", part), zap.String("id", id), zap.String("translated", joined[i].String()))
+			return nil, false
+		}
+		translatedTexts[i] = unmasked
+	}
+
+	// Reassemble the DOM
+	result, err := segments.Render(translatedTexts)
+	if err != nil {
+		a.l.Error("failed to render translated segments", zap.String("part", part), zap.String("id", id), zap.Error(err))
+		return nil, false
+	}
+
+	return &result, sent
+}
@@ -2,23 +2,38 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 
 	"github.com/candinya/rsshub-smart-layer/app"
+	"github.com/candinya/rsshub-smart-layer/modules/translate/providers"
+	_ "github.com/candinya/rsshub-smart-layer/modules/translate/providers/builtin"
 	"github.com/candinya/rsshub-smart-layer/types"
 	"gopkg.in/yaml.v3"
 )
 
-var configPath string
+var (
+	configPath    string
+	listProviders bool
+)
 
 func init() {
 	flag.StringVar(&configPath, "config", "config.yml", "path to config file")
+	flag.BoolVar(&listProviders, "list-providers", false, "list registered translate providers and their settings")
 }
 
 func main() {
 	flag.Parse()
 
+	// List providers
+	if listProviders {
+		for _, p := range providers.List() {
+			fmt.Printf("%s\n---\n%s\n", p.Name, p.Schema)
+		}
+		return
+	}
+
 	// Read config
 	configFileBytes, err := os.ReadFile(configPath)
 	if err != nil {
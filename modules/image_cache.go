@@ -0,0 +1,132 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	ImageCacheBackendDisk  = "disk"
+	ImageCacheBackendRedis = "redis"
+)
+
+const (
+	imageCacheDefaultTTL           = 24 * time.Hour
+	imageCacheDefaultMaxObjectSize = 1 << 20 // 1 MiB, fits Redis well
+)
+
+type imageCacheEntry struct {
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+	Expires      time.Time
+	Body         []byte
+}
+
+type imageCache interface {
+	// Get: Returns nil without error on miss
+	Get(ctx context.Context, key string) (*imageCacheEntry, error)
+	Set(ctx context.Context, key string, entry *imageCacheEntry) error
+}
+
+func newImageCache(cfg *types.ConfigImageProxyCache, redisClient *redis.Client, prefix string, l *zap.Logger) (imageCache, error) {
+	switch cfg.Backend {
+	case ImageCacheBackendDisk:
+		return newImageDiskCache(cfg.Dir, cfg.MaxSize, l)
+	case ImageCacheBackendRedis:
+		if redisClient == nil {
+			return nil, fmt.Errorf("redis is not available")
+		}
+		return &imageRedisCache{
+			redis:  redisClient,
+			prefix: prefix,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown image cache backend: %s", cfg.Backend)
+	}
+}
+
+// imageCacheKey: Cache key by platform, source and variant, as rules of platform affect the response
+func imageCacheKey(src string, platform string, variant string) string {
+	hash := sha256.Sum256([]byte(platform + "\x00" + src + "\x00" + variant))
+	return hex.EncodeToString(hash[:])
+}
+
+func imageETag(body []byte) string {
+	hash := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+func encodeImageCacheEntry(entry *imageCacheEntry) ([]byte, error) {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(entry); err != nil {
+		return nil, fmt.Errorf("failed to encode entry: %w", err)
+	}
+
+	return b.Bytes(), nil
+}
+
+func decodeImageCacheEntry(data []byte) (*imageCacheEntry, error) {
+	var entry imageCacheEntry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entry); err != nil {
+		return nil, fmt.Errorf("failed to decode entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// upstreamCacheTTL: Get TTL allowed by upstream Cache-Control / Expires, false if response must not be cached
+func upstreamCacheTTL(header http.Header, defaultTTL time.Duration) (time.Duration, bool) {
+	var (
+		maxAge    = -1
+		sharedAge = -1
+	)
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache", "private":
+			return 0, false
+		case "max-age":
+			if age, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				maxAge = age
+			}
+		case "s-maxage":
+			if age, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				sharedAge = age
+			}
+		}
+	}
+
+	// Shared cache directive takes precedence
+	if sharedAge >= 0 {
+		maxAge = sharedAge
+	}
+	if maxAge == 0 {
+		return 0, false
+	} else if maxAge > 0 {
+		return time.Duration(maxAge) * time.Second, true
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			return 0, false // Invalid Expires means already expired
+		}
+		ttl := time.Until(expiresAt)
+		return ttl, ttl > 0
+	}
+
+	return defaultTTL, true
+}
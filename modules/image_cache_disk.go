@@ -0,0 +1,204 @@
+package modules
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// imageDiskCache: One file per entry, evicted by least recently used when total size exceeds limit
+type imageDiskCache struct {
+	l *zap.Logger
+
+	dir     string
+	maxSize int64
+
+	lock  sync.Mutex
+	lru   *list.List // Front is most recently used
+	files map[string]*list.Element
+	size  int64
+}
+
+type imageDiskCacheFile struct {
+	key  string
+	size int64
+}
+
+func newImageDiskCache(dir string, maxSize int64, l *zap.Logger) (*imageDiskCache, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("empty cache dir")
+	}
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("invalid cache max size: %d", maxSize)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+
+	c := &imageDiskCache{
+		l:       l,
+		dir:     dir,
+		maxSize: maxSize,
+		lru:     list.New(),
+		files:   make(map[string]*list.Element),
+	}
+
+	// Rebuild index from existing files, by modify time which is updated on access
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache dir: %w", err)
+	}
+
+	var infos []fs.FileInfo
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if strings.HasPrefix(entry.Name(), ".tmp-") {
+			_ = os.Remove(filepath.Join(dir, entry.Name())) // Left by interrupted writes
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		c.files[info.Name()] = c.lru.PushBack(&imageDiskCacheFile{
+			key:  info.Name(),
+			size: info.Size(),
+		})
+		c.size += info.Size()
+	}
+	c.evict()
+
+	return c, nil
+}
+
+func (c *imageDiskCache) Get(_ context.Context, key string) (*imageCacheEntry, error) {
+	c.lock.Lock()
+	elem, ok := c.files[key]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.lock.Unlock()
+
+	if !ok {
+		return nil, nil
+	}
+
+	filePath := filepath.Join(c.dir, key)
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		c.remove(key)
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read entry: %w", err)
+	}
+
+	entry, err := decodeImageCacheEntry(data)
+	if err != nil {
+		c.remove(key)
+		return nil, err
+	}
+
+	// Keep access order across restarts
+	now := time.Now()
+	_ = os.Chtimes(filePath, now, now)
+
+	return entry, nil
+}
+
+func (c *imageDiskCache) Set(_ context.Context, key string, entry *imageCacheEntry) error {
+	data, err := encodeImageCacheEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	size := int64(len(data))
+	if size > c.maxSize {
+		return nil // Never fits
+	}
+
+	// Write to temp file then rename, so that readers never see partial entries
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), filepath.Join(c.dir, key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.files[key]; ok {
+		file := elem.Value.(*imageDiskCacheFile)
+		c.size += size - file.size
+		file.size = size
+		c.lru.MoveToFront(elem)
+	} else {
+		c.files[key] = c.lru.PushFront(&imageDiskCacheFile{
+			key:  key,
+			size: size,
+		})
+		c.size += size
+	}
+	c.evict()
+
+	return nil
+}
+
+func (c *imageDiskCache) remove(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.files[key]; ok {
+		c.size -= elem.Value.(*imageDiskCacheFile).size
+		c.lru.Remove(elem)
+		delete(c.files, key)
+	}
+}
+
+// evict: Remove least recently used files until size fits, lock must be held
+func (c *imageDiskCache) evict() {
+	for c.size > c.maxSize {
+		elem := c.lru.Back()
+		if elem == nil {
+			return
+		}
+
+		file := elem.Value.(*imageDiskCacheFile)
+		if err := os.Remove(filepath.Join(c.dir, file.key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			c.l.Warn("failed to evict image cache file", zap.String("key", file.key), zap.Error(err))
+		}
+		c.size -= file.size
+		c.lru.Remove(elem)
+		delete(c.files, file.key)
+	}
+}
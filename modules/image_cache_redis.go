@@ -0,0 +1,43 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type imageRedisCache struct {
+	redis  *redis.Client
+	prefix string
+}
+
+func (c *imageRedisCache) key(key string) string {
+	return fmt.Sprintf("%simage:%s", c.prefix, key)
+}
+
+func (c *imageRedisCache) Get(ctx context.Context, key string) (*imageCacheEntry, error) {
+	data, err := c.redis.Get(ctx, c.key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get entry: %w", err)
+	}
+
+	return decodeImageCacheEntry(data)
+}
+
+func (c *imageRedisCache) Set(ctx context.Context, key string, entry *imageCacheEntry) error {
+	data, err := encodeImageCacheEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	if err = c.redis.Set(ctx, c.key(key), data, time.Until(entry.Expires)).Err(); err != nil {
+		return fmt.Errorf("failed to set entry: %w", err)
+	}
+
+	return nil
+}
@@ -2,12 +2,18 @@ package modules
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/candinya/rsshub-smart-layer/types"
 	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
@@ -16,32 +22,184 @@ import (
 type ImageProxy struct {
 	l *zap.Logger
 
-	path  string
-	rules map[string]types.ConfigImageProxyRule
+	path      string
+	rules     map[string]types.ConfigImageProxyRule
+	hosts     map[string]types.ConfigImageProxyRule
+	transport *http.Transport
+	signer    *imageProxySigner
+	compact   bool
+
+	cache    imageCache
+	cacheCfg types.ConfigImageProxyCache
+
+	transformMaxSize int64
+
+	attrs         imageProxyAttributes
+	platformAttrs map[string]imageProxyAttributes
+
+	maxSize int64
+	timeout time.Duration
+
+	baseURL        *url.URL
+	trustForwarded bool
+
+	allowPrivate bool
+	allowHosts   []string
+	fallback     *imageProxyFallback
+
+	include     []string
+	exclude     []string
+	skipProxied bool
 }
 
-func NewImageProxy(cfg *types.ConfigImageProxy, l *zap.Logger) *ImageProxy {
-	return &ImageProxy{
+func NewImageProxy(cfg *types.ConfigImageProxy, redisClient *redis.Client, prefix string, l *zap.Logger) (*ImageProxy, error) {
+	p := &ImageProxy{
 		l:     l,
 		path:  cfg.Path,
 		rules: cfg.Rules,
+		hosts: cfg.Hosts,
+
+		transport: newImageProxyTransport(cfg.AllowPrivate),
+		compact:   cfg.Compact,
+
+		transformMaxSize: cfg.TransformMaxSize,
+
+		maxSize: cfg.MaxSize,
+		timeout: cfg.Timeout,
+
+		include:     cfg.Include,
+		exclude:     cfg.Exclude,
+		skipProxied: cfg.SkipProxied,
+
+		trustForwarded: cfg.TrustForwarded,
+
+		allowPrivate: cfg.AllowPrivate,
+		allowHosts:   cfg.AllowHosts,
+
+		attrs:         newImageProxyAttributes(cfg.Attributes),
+		platformAttrs: make(map[string]imageProxyAttributes),
+	}
+	for platform, rule := range cfg.Rules {
+		if len(rule.Attributes) > 0 {
+			p.platformAttrs[platform] = newImageProxyAttributes(rule.Attributes)
+		}
+	}
+	if cfg.Fallback != nil {
+		var err error
+		p.fallback, err = newImageProxyFallback(cfg.Fallback)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize fallback: %w", err)
+		}
 	}
+	if cfg.BaseURL != "" {
+		var err error
+		p.baseURL, err = url.Parse(cfg.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse base url: %w", err)
+		}
+		if (p.baseURL.Scheme != "http" && p.baseURL.Scheme != "https") || p.baseURL.Host == "" {
+			return nil, fmt.Errorf("base url must be absolute http(s) url: %s", cfg.BaseURL)
+		}
+	}
+	if p.transformMaxSize <= 0 {
+		p.transformMaxSize = imageTransformDefaultMaxSource
+	}
+
+	// Platform is chosen by client in unsigned links, so per platform allowlists could be bypassed
+	if cfg.Sign == nil {
+		for platform, rule := range cfg.Rules {
+			if len(rule.AllowHosts) > 0 {
+				return nil, fmt.Errorf("allow_hosts of platform %s requires sign, use global allow_hosts for unsigned links", platform)
+			}
+		}
+	}
+
+	if cfg.Sign != nil {
+		var err error
+		p.signer, err = newImageProxySigner(cfg.Sign)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize signer: %w", err)
+		}
+	}
+
+	if cfg.Cache != nil {
+		var err error
+		p.cache, err = newImageCache(cfg.Cache, redisClient, prefix, l)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize cache: %w", err)
+		}
+
+		p.cacheCfg = *cfg.Cache
+		if p.cacheCfg.DefaultTTL <= 0 {
+			p.cacheCfg.DefaultTTL = imageCacheDefaultTTL
+		}
+		if p.cacheCfg.MaxObjectSize <= 0 {
+			p.cacheCfg.MaxObjectSize = imageCacheDefaultMaxObjectSize
+		}
+	}
+
+	return p, nil
 }
 
-func (p *ImageProxy) ProcessLink(src string, host string, platform string) string {
+func (p *ImageProxy) ProcessLink(src string, base *url.URL, platform string) string {
+	// Check if source should be proxied
+	if !p.shouldProxy(src, base, platform) {
+		p.l.Debug("skip proxy link", zap.String("src", src), zap.String("platform", platform))
+		return src
+	}
+
+	// Sign
+	var sig string
+	if p.signer != nil {
+		sig = p.signer.Sign(src, platform)
+	}
+
+	// Compact: {path}/{platform}/{base64url source}[/{signature}]
+	if p.compact {
+		proxyPath := strings.TrimSuffix(p.path, "/") + "/" + url.PathEscape(platform) + "/" + base64.RawURLEncoding.EncodeToString([]byte(src))
+		if sig != "" {
+			proxyPath += "/" + sig
+		}
+
+		return (&url.URL{
+			Scheme: base.Scheme,
+			Host:   base.Host,
+			Path:   strings.TrimSuffix(base.Path, "/") + proxyPath,
+		}).String()
+	}
+
 	// Prepare query
 	query := url.Values{}
 	query.Set("s", src)
 	query.Set("p", platform)
+	if sig != "" {
+		query.Set("sig", sig)
+	}
 
 	return (&url.URL{
-		Host:     host,
-		Path:     p.path,
+		Scheme:   base.Scheme,
+		Host:     base.Host,
+		Path:     strings.TrimSuffix(base.Path, "/") + p.path,
 		RawQuery: query.Encode(),
 	}).String()
 }
 
-func (p *ImageProxy) ProcessHTML(contentWithoutProxy string, host string, platform string) string {
+// parseRequest: Get source, platform and signature from either query or compact path
+func (p *ImageProxy) parseRequest(c echo.Context) (string, string, string, error) {
+	encoded := c.Param("src")
+	if encoded == "" {
+		return c.QueryParam("s"), c.QueryParam("p"), c.QueryParam("sig"), nil
+	}
+
+	src, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to decode src: %w", err)
+	}
+
+	return string(src), c.Param("platform"), c.Param("sig"), nil
+}
+
+func (p *ImageProxy) ProcessHTML(contentWithoutProxy string, base *url.URL, platform string) string {
 	// Parse HTML
 	p.l.Debug("start process html")
 	parsed, err := html.ParseFragment(strings.NewReader(contentWithoutProxy), &html.Node{
@@ -54,11 +212,11 @@ func (p *ImageProxy) ProcessHTML(contentWithoutProxy string, host string, platfo
 		return contentWithoutProxy // Keep untouched
 	}
 
-	// Replace all src from img tags
+	// Replace all media links
 	var b bytes.Buffer
 	for _, node := range parsed {
-		p.l.Debug("proxy all img tags", zap.Any("node", node))
-		p.traverseHTMLTree(node, host, platform)
+		p.l.Debug("proxy all media links", zap.Any("node", node))
+		p.traverseHTMLTree(node, base, platform)
 
 		p.l.Debug("render back to html", zap.Any("node", node))
 		err = html.Render(&b, node)
@@ -72,57 +230,319 @@ func (p *ImageProxy) ProcessHTML(contentWithoutProxy string, host string, platfo
 	return b.String()
 }
 
-func (p *ImageProxy) traverseHTMLTree(n *html.Node, host string, platform string) {
-	// Find all img tags
-	if n.Type == html.ElementNode && n.Data == "img" {
-		p.l.Debug("found an img tag", zap.Any("node", n))
+func (p *ImageProxy) traverseHTMLTree(n *html.Node, base *url.URL, platform string) {
+	// Find all media attributes
+	if n.Type == html.ElementNode {
+		attrs := p.attributes(platform)
 		for i, a := range n.Attr {
-			if a.Key == "src" {
-				proxied := p.ProcessLink(a.Val, host, platform)
-				p.l.Debug("replace src", zap.String("old", a.Val), zap.String("new", proxied))
-				n.Attr[i].Val = proxied
+			if a.Namespace != "" || !attrs.match(n.Data, a.Key) {
+				continue
 			}
+
+			proxied := rewriteAttribute(a.Key, a.Val, func(src string) string {
+				return p.ProcessLink(src, base, platform)
+			})
+			p.l.Debug("replace attribute", zap.String("tag", n.Data), zap.String("key", a.Key), zap.String("old", a.Val), zap.String("new", proxied))
+			n.Attr[i].Val = proxied
 		}
 	}
 
 	// Traverse children
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		p.traverseHTMLTree(c, host, platform)
+		p.traverseHTMLTree(c, base, platform)
 	}
 }
 
+// attributes: Get attribute rules of platform, fallback to global ones
+func (p *ImageProxy) attributes(platform string) imageProxyAttributes {
+	if attrs, ok := p.platformAttrs[platform]; ok {
+		return attrs
+	}
+
+	return p.attrs
+}
+
 func (p *ImageProxy) Proxy(c echo.Context) error {
-	imageSrc := c.QueryParam("s")
-	platform := c.QueryParam("p")
+	imageSrc, platform, sig, err := p.parseRequest(c)
+	if err != nil {
+		p.l.Debug("image proxy invalid request", zap.Error(err))
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	// Verify signature
+	if p.signer != nil {
+		if err = p.signer.Verify(imageSrc, platform, sig); err != nil {
+			p.l.Debug("image proxy signature rejected", zap.String("src", imageSrc), zap.String("platform", platform), zap.Error(err))
+			return c.NoContent(http.StatusForbidden)
+		}
+	}
 
-	imageRequest, err := http.NewRequest("GET", imageSrc, nil)
+	// Check destination
+	imageURL, err := url.Parse(imageSrc)
 	if err != nil {
-		p.l.Error("image proxy new request", zap.String("src", imageSrc), zap.String("platform", platform), zap.Error(err))
-		return c.NoContent(http.StatusInternalServerError)
+		p.l.Debug("image proxy invalid src", zap.String("src", imageSrc), zap.Error(err))
+		return c.NoContent(http.StatusBadRequest)
+	}
+	if err = p.checkURL(imageURL, platform); err != nil {
+		p.l.Debug("image proxy destination not allowed", zap.String("src", imageSrc), zap.String("platform", platform), zap.Error(err))
+		return c.NoContent(http.StatusForbidden)
 	}
 
-	// Apply platform specific rules
-	if _, ok := p.rules[platform]; ok {
-		if p.rules[platform].Origin != nil {
-			imageRequest.Header.Add("Origin", *p.rules[platform].Origin)
+	// Parse transform, over platform defaults
+	var transformDefaults *types.ConfigImageTransform
+	if rule, ok := p.rules[platform]; ok {
+		transformDefaults = rule.Transform
+	}
+	// Signature doesn't cover query parameters, so only defaults are applied for signed links
+	query := c.QueryParams()
+	if p.signer != nil {
+		query = nil
+	}
+	transform, err := parseImageTransform(query, transformDefaults)
+	if err != nil {
+		p.l.Debug("image proxy invalid transform", zap.String("src", imageSrc), zap.Error(err))
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	// Serve from cache, transformed variants are cached separately
+	cacheKey := imageCacheKey(imageURL.String(), platform, transform.String())
+	if p.cache != nil {
+		entry, err := p.cache.Get(c.Request().Context(), cacheKey)
+		if err != nil {
+			p.l.Warn("failed to get image cache", zap.String("src", imageSrc), zap.Error(err))
+		} else if entry != nil && time.Now().Before(entry.Expires) {
+			p.l.Debug("image proxy cache hit", zap.String("src", imageSrc))
+			return p.serveEntry(c, entry)
 		}
-		if p.rules[platform].Referer != nil {
-			imageRequest.Header.Add("Referer", *p.rules[platform].Referer)
+	}
+
+	// Apply timeout for the whole upstream request, including body
+	ctx := c.Request().Context()
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	// Forward range headers, so that media can be seeked
+	forward := make(http.Header)
+	for _, key := range imageProxyForwardHeaders {
+		if value := c.Request().Header.Get(key); value != "" {
+			forward.Set(key, value)
+		}
+	}
+
+	// Skip sources failed recently
+	failureKey := imageCacheKey(imageURL.String(), platform, "")
+	if p.fallback != nil && p.fallback.negative != nil {
+		if status, failed := p.fallback.negative.Get(failureKey); failed {
+			p.l.Debug("image proxy negative cache hit", zap.String("src", imageSrc), zap.Int("status", status))
+			return p.serveFailure(c, status)
 		}
 	}
 
-	// Execute request
-	res, err := http.DefaultClient.Do(imageRequest)
+	// Execute request, with fallbacks
+	res, err := p.fetchWithFallback(ctx, imageURL, platform, forward)
+	if err == nil && transform != nil && res.StatusCode == http.StatusPartialContent && strings.HasPrefix(res.Header.Get("Content-Type"), "image/") {
+		// Image is going to be transformed, fetch it whole and apply range on the result instead
+		p.l.Debug("image proxy fetch whole image for transform", zap.String("src", imageSrc))
+		res.Body.Close()
+		res, err = p.fetchWithFallback(ctx, imageURL, platform, make(http.Header))
+	}
 	if err != nil {
 		p.l.Error("image proxy execute request", zap.String("src", imageSrc), zap.String("platform", platform), zap.Error(err))
-		return c.NoContent(http.StatusInternalServerError)
+
+		// Canceled by client says nothing about the source, so it's not remembered
+		if p.fallback != nil && p.fallback.negative != nil && c.Request().Context().Err() == nil {
+			p.fallback.negative.Set(failureKey, 0, p.fallback.negativeTTL)
+		}
+		return p.serveFailure(c, 0)
 	}
 
 	defer res.Body.Close()
 
-	return c.Stream(res.StatusCode, res.Header.Get("Content-Type"), res.Body)
+	if p.fallback != nil && p.fallback.statuses[res.StatusCode] {
+		p.l.Debug("image proxy all attempts failed", zap.String("src", imageSrc), zap.Int("status", res.StatusCode))
+		if p.fallback.negative != nil {
+			p.fallback.negative.Set(failureKey, res.StatusCode, p.fallback.negativeTTL)
+		}
+		return p.serveFailure(c, res.StatusCode)
+	}
+
+	// Enforce max object size
+	if p.maxSize > 0 {
+		if size := responseObjectSize(res); size > p.maxSize {
+			p.l.Debug("image proxy object too large", zap.String("src", imageSrc), zap.Int64("size", size))
+			return c.NoContent(http.StatusBadGateway)
+		}
+		res.Body = &maxBytesReader{ReadCloser: res.Body, remaining: p.maxSize}
+	}
+
+	// Check if response needs to be buffered for cache or transform
+	var (
+		ttl       time.Duration
+		cacheable bool
+	)
+	if p.cache != nil && res.StatusCode == http.StatusOK {
+		ttl, cacheable = upstreamCacheTTL(res.Header, p.cacheCfg.DefaultTTL)
+	}
+	transformable := transform != nil && res.StatusCode == http.StatusOK && strings.HasPrefix(res.Header.Get("Content-Type"), "image/")
+	if !cacheable && !transformable {
+		return p.stream(c, res, res.Body)
+	}
+
+	readLimit := p.cacheCfg.MaxObjectSize
+	if transformable {
+		readLimit = p.transformMaxSize
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, readLimit+1))
+	if err != nil {
+		p.l.Error("image proxy read response", zap.String("src", imageSrc), zap.Error(err))
+		return c.NoContent(http.StatusBadGateway)
+	}
+
+	if int64(len(body)) > readLimit {
+		// Too large, stream the rest as is
+		return p.stream(c, res, io.MultiReader(bytes.NewReader(body), res.Body))
+	}
+
+	// Transform
+	contentType := res.Header.Get("Content-Type")
+	if transformable {
+		body, contentType, err = transform.Apply(body, contentType)
+		if err != nil {
+			p.l.Debug("image proxy transform failed, keep original", zap.String("src", imageSrc), zap.Error(err))
+		}
+	}
+
+	entry := &imageCacheEntry{
+		ContentType: contentType,
+		ETag:        imageETag(body),
+		Expires:     time.Now().Add(ttl),
+		Body:        body,
+	}
+	if lastModified, err := http.ParseTime(res.Header.Get("Last-Modified")); err == nil {
+		entry.LastModified = lastModified
+	}
+
+	// Cache successful responses allowed by upstream
+	if cacheable && int64(len(body)) <= p.cacheCfg.MaxObjectSize {
+		if err = p.cache.Set(c.Request().Context(), cacheKey, entry); err != nil {
+			p.l.Warn("failed to set image cache", zap.String("src", imageSrc), zap.Error(err))
+		}
+	}
+
+	return p.serveEntry(c, entry)
+}
+
+// fetch: Request source with platform specific rules
+func (p *ImageProxy) fetch(ctx context.Context, imageURL *url.URL, platform string, forward http.Header, attempt imageProxyAttempt) (*http.Response, error) {
+	// Connection through outbound proxy is not checked by dialer
+	if attempt.viaProxy {
+		if err := p.checkResolved(ctx, imageURL.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+
+	imageRequest, err := http.NewRequestWithContext(ctx, "GET", imageURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for key, values := range forward {
+		imageRequest.Header[key] = values
+	}
+
+	// Apply platform rule, then alternative rule over it, then source host rule
+	var platformRule *types.ConfigImageProxyRule
+	if rule, ok := p.rules[platform]; ok {
+		platformRule = &rule
+	}
+	hostRule, _ := p.hostRule(imageURL.Hostname())
+	applyRuleHeaders(imageRequest.Header, platformRule, attempt.rule, hostRule)
+
+	return p.client(platform, attempt.viaProxy).Do(imageRequest)
+}
+
+// stream: Pass upstream response through with its entity headers
+func (p *ImageProxy) stream(c echo.Context, res *http.Response, body io.Reader) error {
+	header := c.Response().Header()
+	for _, key := range imageProxyPassHeaders {
+		if value := res.Header.Get(key); value != "" {
+			header.Set(key, value)
+		}
+	}
+
+	return c.Stream(res.StatusCode, res.Header.Get("Content-Type"), body)
+}
+
+// serveEntry: Serve cached entry, conditional requests are answered with 304
+func (p *ImageProxy) serveEntry(c echo.Context, entry *imageCacheEntry) error {
+	maxAge := time.Until(entry.Expires)
+	if maxAge > 0 && p.cacheCfg.ClientMaxAge > 0 {
+		maxAge = p.cacheCfg.ClientMaxAge
+	}
+
+	header := c.Response().Header()
+	header.Set("Content-Type", entry.ContentType)
+	header.Set("ETag", entry.ETag)
+	if maxAge > 0 {
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(maxAge/time.Second)))
+	} else {
+		header.Set("Cache-Control", "no-cache") // Not cacheable by upstream policy
+	}
+
+	http.ServeContent(c.Response(), c.Request(), "", entry.LastModified, bytes.NewReader(entry.Body))
+	return nil
+}
+
+// BaseURL: Public base URL of proxy links, configured or derived from request
+func (p *ImageProxy) BaseURL(req *http.Request) *url.URL {
+	if p.baseURL != nil {
+		return p.baseURL
+	}
+
+	base := &url.URL{
+		Scheme: "http",
+		Host:   req.Host,
+	}
+	if req.TLS != nil {
+		base.Scheme = "https"
+	}
+
+	// Only trust forwarded headers when behind a reverse proxy which sets them
+	if p.trustForwarded {
+		if proto := firstForwarded(req.Header.Get("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+			base.Scheme = proto
+		}
+		if host := firstForwarded(req.Header.Get("X-Forwarded-Host")); host != "" {
+			base.Host = host
+		}
+	}
+
+	return base
+}
+
+// firstForwarded: First value of comma separated forwarded header, set by the outermost proxy
+func firstForwarded(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.ToLower(strings.TrimSpace(first))
 }
 
 func (p *ImageProxy) Path() string {
 	return p.path
 }
+
+// Paths: Routes to register, including compact ones if enabled
+func (p *ImageProxy) Paths() []string {
+	paths := []string{p.path}
+	if p.compact {
+		base := strings.TrimSuffix(p.path, "/")
+		paths = append(paths, base+"/:platform/:src")
+		if p.signer != nil {
+			paths = append(paths, base+"/:platform/:src/:sig")
+		}
+	}
+
+	return paths
+}
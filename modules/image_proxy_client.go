@@ -0,0 +1,140 @@
+package modules
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const imageProxyMaxRedirects = 10
+
+// Reserved ranges not covered by netip.Addr methods
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // TEST-NET-1
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // TEST-NET-2
+	netip.MustParsePrefix("203.0.113.0/24"),  // TEST-NET-3
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved, including broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // Local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+}
+
+// isBlockedAddr: Check if address is private, loopback, link-local or otherwise not publicly routable
+func isBlockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newImageProxyTransport: Transport which refuses to connect to blocked addresses after DNS resolution,
+// so it also works for redirects and DNS rebinding
+func newImageProxyTransport(allowPrivate bool) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("failed to parse address %s: %w", address, err)
+			}
+
+			if isBlockedAddr(addrPort.Addr()) {
+				return fmt.Errorf("address %s is not allowed", address)
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // Environment proxy would bypass address check
+	transport.DialContext = dialer.DialContext
+
+	return transport
+}
+
+// checkURL: Check scheme and host allowlist of platform
+func (p *ImageProxy) checkURL(u *url.URL, platform string) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme %s is not allowed", u.Scheme)
+	}
+
+	if u.Hostname() == "" {
+		return fmt.Errorf("empty host")
+	}
+
+	// Platform allowlist, or global one for platforms without
+	allowHosts := p.allowHosts
+	if rule, ok := p.rules[platform]; ok && len(rule.AllowHosts) > 0 {
+		allowHosts = rule.AllowHosts
+	}
+	if len(allowHosts) > 0 && !matchHost(allowHosts, u.Hostname()) {
+		return fmt.Errorf("host %s is not allowed for platform %s", u.Hostname(), platform)
+	}
+
+	return nil
+}
+
+// client: Get client which checks every redirect with platform rules
+func (p *ImageProxy) client(platform string, viaProxy bool) *http.Client {
+	transport := p.transport
+	if viaProxy {
+		transport = p.fallback.transport
+	}
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= imageProxyMaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", imageProxyMaxRedirects)
+			}
+
+			if err := p.checkURL(req.URL, platform); err != nil {
+				return err
+			}
+			if viaProxy {
+				return p.checkResolved(req.Context(), req.URL.Hostname())
+			}
+
+			return nil
+		},
+	}
+}
+
+// matchHost: Match hostname with glob patterns, e.g. *.pximg.net
+func matchHost(patterns []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(strings.ToLower(pattern), host); matched {
+			return true
+		}
+	}
+
+	return false
+}
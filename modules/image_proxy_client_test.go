@@ -0,0 +1,93 @@
+package modules
+
+import (
+	"net/netip"
+	"net/url"
+	"testing"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+	"go.uber.org/zap"
+)
+
+func TestIsBlockedAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		{"8.8.8.8", false},
+		{"1.1.1.1", false},
+		{"2606:4700::1111", false},
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true}, // Cloud metadata
+		{"100.64.0.1", true},      // Carrier-grade NAT
+		{"0.0.0.0", true},
+		{"255.255.255.255", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fc00::1", true},
+		{"::ffff:127.0.0.1", true}, // IPv4-mapped
+		{"::ffff:8.8.8.8", false},
+		{"64:ff9b::a00:1", true}, // NAT64
+	}
+
+	for _, tt := range tests {
+		if got := isBlockedAddr(netip.MustParseAddr(tt.addr)); got != tt.blocked {
+			t.Errorf("isBlockedAddr(%s) = %v, want %v", tt.addr, got, tt.blocked)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	p, err := NewImageProxy(&types.ConfigImageProxy{
+		Path:       "/image-proxy",
+		AllowHosts: []string{"*.example.com"},
+		Rules: map[string]types.ConfigImageProxyRule{
+			"twitter": {AllowHosts: []string{"pbs.twimg.com"}},
+		},
+		Sign: &types.ConfigImageProxySign{Keys: []string{"key"}},
+	}, nil, "", zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		src      string
+		platform string
+		ok       bool
+	}{
+		{"https://pbs.twimg.com/a.jpg", "twitter", true},
+		{"https://abs.twimg.com/a.jpg", "twitter", false},
+		{"https://img.example.com/a.jpg", "twitter", false},
+		{"https://img.example.com/a.jpg", "unknown", true},
+		{"https://pbs.twimg.com/a.jpg", "unknown", false}, // Global allowlist for platforms without rule
+		{"ftp://img.example.com/a.jpg", "unknown", false},
+		{"file:///etc/passwd", "unknown", false},
+		{"/relative.jpg", "unknown", false},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse(tt.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = p.checkURL(u, tt.platform); (err == nil) != tt.ok {
+			t.Errorf("checkURL(%s, %s) = %v, want ok %v", tt.src, tt.platform, err, tt.ok)
+		}
+	}
+}
+
+func TestPlatformAllowHostsRequiresSign(t *testing.T) {
+	_, err := NewImageProxy(&types.ConfigImageProxy{
+		Path: "/image-proxy",
+		Rules: map[string]types.ConfigImageProxyRule{
+			"twitter": {AllowHosts: []string{"pbs.twimg.com"}},
+		},
+	}, nil, "", zap.NewNop())
+	if err == nil {
+		t.Error("NewImageProxy() with platform allow_hosts but no sign should fail")
+	}
+}
@@ -0,0 +1,255 @@
+package modules
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// Upstream statuses treated as failure by default
+var defaultImageProxyFallbackStatuses = []int{
+	http.StatusForbidden,
+	http.StatusNotFound,
+	http.StatusGone,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+const imageNegativeCacheMaxEntries = 10000
+
+type imageProxyFallback struct {
+	statuses  map[int]bool
+	transport *http.Transport // Through outbound proxy, nil if not configured
+
+	placeholder            []byte
+	placeholderContentType string
+
+	negativeTTL time.Duration
+	negative    *imageNegativeCache
+}
+
+// imageProxyAttempt: One way to fetch source
+type imageProxyAttempt struct {
+	rule     *types.ConfigImageProxyRule // Applied over platform rule, nil to use platform rule only
+	viaProxy bool
+}
+
+func newImageProxyFallback(cfg *types.ConfigImageProxyFallback) (*imageProxyFallback, error) {
+	f := &imageProxyFallback{
+		statuses:    make(map[int]bool),
+		negativeTTL: cfg.NegativeTTL,
+	}
+
+	statuses := cfg.Statuses
+	if len(statuses) == 0 {
+		statuses = defaultImageProxyFallbackStatuses
+	}
+	for _, status := range statuses {
+		f.statuses[status] = true
+	}
+
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse proxy url: %w", err)
+		}
+		f.transport = newImageProxyOutboundTransport(proxyURL)
+	}
+
+	if cfg.Placeholder != "" {
+		placeholder, err := os.ReadFile(cfg.Placeholder)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read placeholder: %w", err)
+		}
+		f.placeholder = placeholder
+		f.placeholderContentType = http.DetectContentType(placeholder)
+	}
+
+	if f.negativeTTL > 0 {
+		f.negative = newImageNegativeCache(imageNegativeCacheMaxEntries)
+	}
+
+	return f, nil
+}
+
+// newImageProxyOutboundTransport: Transport through trusted outbound proxy, destination addresses are checked before request
+// as connection is established by the proxy
+func newImageProxyOutboundTransport(proxyURL *url.URL) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+
+	return transport
+}
+
+// checkResolved: Check all resolved addresses of host, for requests where connection is not made by us
+func (p *ImageProxy) checkResolved(ctx context.Context, host string) error {
+	if p.allowPrivate {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if isBlockedAddr(addr) {
+			return fmt.Errorf("address %s of %s is not allowed", addr, host)
+		}
+	}
+
+	return nil
+}
+
+// attempts: Primary attempt, then alternative rules of platform, then through outbound proxy
+func (p *ImageProxy) attempts(platform string) []imageProxyAttempt {
+	attempts := []imageProxyAttempt{{}}
+	if p.fallback == nil {
+		return attempts
+	}
+
+	if rule, ok := p.rules[platform]; ok {
+		for i := range rule.Alternatives {
+			attempts = append(attempts, imageProxyAttempt{
+				rule: &rule.Alternatives[i],
+			})
+		}
+	}
+
+	if p.fallback.transport != nil {
+		attempts = append(attempts, imageProxyAttempt{
+			viaProxy: true,
+		})
+	}
+
+	return attempts
+}
+
+// fetchWithFallback: Try attempts in order until one succeeds, the last failure is returned if all fail
+func (p *ImageProxy) fetchWithFallback(ctx context.Context, imageURL *url.URL, platform string, forward http.Header) (*http.Response, error) {
+	attempts := p.attempts(platform)
+
+	var (
+		res *http.Response
+		err error
+	)
+	for i, attempt := range attempts {
+		if res != nil {
+			res.Body.Close()
+		}
+
+		res, err = p.fetch(ctx, imageURL, platform, forward, attempt)
+		if err == nil && (p.fallback == nil || !p.fallback.statuses[res.StatusCode]) {
+			return res, nil
+		}
+
+		p.l.Debug("image proxy attempt failed", zap.String("src", imageURL.String()), zap.Int("attempt", i), zap.Bool("via_proxy", attempt.viaProxy), zap.Error(err))
+
+		// No time left for other attempts
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return res, err
+}
+
+// serveFailure: Serve placeholder if configured, otherwise the upstream status
+func (p *ImageProxy) serveFailure(c echo.Context, status int) error {
+	if p.fallback != nil && p.fallback.placeholder != nil {
+		header := c.Response().Header()
+		if p.fallback.negativeTTL > 0 {
+			header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(p.fallback.negativeTTL/time.Second)))
+		} else {
+			header.Set("Cache-Control", "no-cache")
+		}
+
+		return c.Blob(http.StatusOK, p.fallback.placeholderContentType, p.fallback.placeholder)
+	}
+
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	return c.NoContent(status)
+}
+
+// imageNegativeCache: Recently failed sources in memory, the oldest ones are evicted when full
+type imageNegativeCache struct {
+	maxEntries int
+
+	lock    sync.Mutex
+	lru     *list.List // Front is most recently set
+	entries map[string]*list.Element
+}
+
+type imageNegativeCacheEntry struct {
+	key     string
+	status  int // 0 for request error
+	expires time.Time
+}
+
+func newImageNegativeCache(maxEntries int) *imageNegativeCache {
+	return &imageNegativeCache{
+		maxEntries: maxEntries,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get: Get status of failed source, false if not failed recently
+func (n *imageNegativeCache) Get(key string) (int, bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	elem, ok := n.entries[key]
+	if !ok {
+		return 0, false
+	}
+	entry := elem.Value.(*imageNegativeCacheEntry)
+	if time.Now().After(entry.expires) {
+		n.lru.Remove(elem)
+		delete(n.entries, key)
+		return 0, false
+	}
+
+	return entry.status, true
+}
+
+func (n *imageNegativeCache) Set(key string, status int, ttl time.Duration) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	expires := time.Now().Add(ttl)
+	if elem, ok := n.entries[key]; ok {
+		entry := elem.Value.(*imageNegativeCacheEntry)
+		entry.status = status
+		entry.expires = expires
+		n.lru.MoveToFront(elem)
+	} else {
+		n.entries[key] = n.lru.PushFront(&imageNegativeCacheEntry{
+			key:     key,
+			status:  status,
+			expires: expires,
+		})
+	}
+
+	// Evict the oldest entries when full
+	for n.lru.Len() > n.maxEntries {
+		elem := n.lru.Back()
+		n.lru.Remove(elem)
+		delete(n.entries, elem.Value.(*imageNegativeCacheEntry).key)
+	}
+}
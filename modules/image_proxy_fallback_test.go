@@ -0,0 +1,188 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func TestImageNegativeCache(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(n *imageNegativeCache)
+		want map[string]int // Key -> status, -1 for missing
+	}{
+		{
+			name: "get set",
+			run: func(n *imageNegativeCache) {
+				n.Set("a", 404, time.Minute)
+				n.Set("b", 0, time.Minute)
+			},
+			want: map[string]int{"a": 404, "b": 0, "c": -1},
+		},
+		{
+			name: "expired",
+			run: func(n *imageNegativeCache) {
+				n.Set("a", 404, -time.Second)
+			},
+			want: map[string]int{"a": -1},
+		},
+		{
+			name: "evict oldest",
+			run: func(n *imageNegativeCache) {
+				n.Set("a", 404, time.Minute)
+				n.Set("b", 404, time.Minute)
+				n.Set("c", 404, time.Minute)
+				n.Set("d", 404, time.Minute)
+			},
+			want: map[string]int{"a": -1, "b": 404, "c": 404, "d": 404},
+		},
+		{
+			name: "set again refreshes",
+			run: func(n *imageNegativeCache) {
+				n.Set("a", 404, time.Minute)
+				n.Set("b", 404, time.Minute)
+				n.Set("c", 404, time.Minute)
+				n.Set("a", 503, time.Minute)
+				n.Set("d", 404, time.Minute)
+			},
+			want: map[string]int{"a": 503, "b": -1, "c": 404, "d": 404},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newImageNegativeCache(3)
+			tt.run(n)
+
+			for key, want := range tt.want {
+				status, ok := n.Get(key)
+				if !ok {
+					status = -1
+				}
+				if status != want {
+					t.Errorf("Get(%s) = %d, want %d", key, status, want)
+				}
+			}
+			if n.lru.Len() != len(n.entries) || n.lru.Len() > 3 {
+				t.Errorf("inconsistent size: list %d, map %d", n.lru.Len(), len(n.entries))
+			}
+		})
+	}
+}
+
+func TestImageProxyAlternatives(t *testing.T) {
+	var (
+		lock    sync.Mutex
+		headers []string
+	)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		headers = append(headers, fmt.Sprintf("%s|%s|%s", r.Header.Get("Referer"), r.Header.Get("User-Agent"), r.Header.Get("X-Host")))
+		lock.Unlock()
+
+		if r.Header.Get("Referer") != "https://b.example.com/" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("image"))
+	}))
+	defer upstream.Close()
+
+	str := func(s string) *string { return &s }
+	upstreamURL, _ := url.Parse(upstream.URL)
+	p, err := NewImageProxy(&types.ConfigImageProxy{
+		Path:         "/img",
+		AllowPrivate: true,
+		Rules: map[string]types.ConfigImageProxyRule{
+			"p": {
+				Referer:   str("https://a.example.com/"),
+				UserAgent: str("test-agent"),
+				Alternatives: []types.ConfigImageProxyRule{
+					{Referer: str("https://b.example.com/")},
+				},
+			},
+		},
+		Hosts: map[string]types.ConfigImageProxyRule{
+			upstreamURL.Hostname(): {Headers: map[string]string{"X-Host": "host"}},
+		},
+		Fallback: &types.ConfigImageProxyFallback{},
+	}, nil, "", zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := url.Values{"s": {upstream.URL + "/a.png"}, "p": {"p"}}
+	rec := httptest.NewRecorder()
+	if err = p.Proxy(echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/img?"+query.Encode(), nil), rec)); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	want := []string{
+		"https://a.example.com/|test-agent|host",
+		"https://b.example.com/|test-agent|host", // Platform user agent is kept
+	}
+	if fmt.Sprint(headers) != fmt.Sprint(want) {
+		t.Errorf("upstream headers = %q, want %q", headers, want)
+	}
+}
+
+func TestImageProxyNegativeCacheCanceled(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	p, err := NewImageProxy(&types.ConfigImageProxy{
+		Path:         "/img",
+		AllowPrivate: true,
+		Fallback:     &types.ConfigImageProxyFallback{NegativeTTL: time.Minute},
+	}, nil, "", zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		src        string
+		cancel     bool
+		wantCached bool
+	}{
+		{"canceled by client", upstream.URL + "/a.png", true, false},
+		{"request error", "http://127.0.0.1:1/a.png", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancel {
+				cancel()
+			} else {
+				defer cancel()
+			}
+
+			query := url.Values{"s": {tt.src}, "p": {"p"}}
+			req := httptest.NewRequest(http.MethodGet, "/img?"+query.Encode(), nil).WithContext(ctx)
+			if err := p.Proxy(echo.New().NewContext(req, httptest.NewRecorder())); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, cached := p.fallback.negative.Get(imageCacheKey(tt.src, "p", "")); cached != tt.wantCached {
+				t.Errorf("negative cached = %v, want %v", cached, tt.wantCached)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package modules
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Default tag -> attributes rewritten through proxy, "*" applies to all tags
+var defaultImageProxyAttributes = map[string][]string{
+	"img":    {"src", "srcset", "data-src", "data-srcset", "data-original"},
+	"video":  {"src", "poster"},
+	"audio":  {"src"},
+	"source": {"src", "srcset", "data-src", "data-srcset"},
+	"*":      {"style"},
+}
+
+var cssURLRegex = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"\s]*))\s*\)`)
+
+// isAbsoluteHTTPURL: Only absolute http(s) links are proxied, relative, data: and other schemes are kept untouched
+func isAbsoluteHTTPURL(src string) bool {
+	u, err := url.Parse(src)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// imageProxyAttributes: Tag -> attribute set
+type imageProxyAttributes map[string]map[string]bool
+
+func newImageProxyAttributes(rules map[string][]string) imageProxyAttributes {
+	if len(rules) == 0 {
+		rules = defaultImageProxyAttributes
+	}
+
+	attrs := make(imageProxyAttributes)
+	for tag, keys := range rules {
+		tag = strings.ToLower(tag)
+		if attrs[tag] == nil {
+			attrs[tag] = make(map[string]bool)
+		}
+		for _, key := range keys {
+			attrs[tag][strings.ToLower(key)] = true
+		}
+	}
+
+	return attrs
+}
+
+func (a imageProxyAttributes) match(tag string, key string) bool {
+	return a[tag][key] || a["*"][key]
+}
+
+// rewriteAttribute: Rewrite attribute value by its kind
+func rewriteAttribute(key string, val string, rewrite func(string) string) string {
+	switch {
+	case key == "style":
+		return rewriteCSSURLs(val, rewrite)
+	case strings.HasSuffix(key, "srcset"):
+		return rewriteSrcset(val, rewrite)
+	default:
+		if strings.TrimSpace(val) == "" {
+			return val
+		}
+		return rewrite(strings.TrimSpace(val))
+	}
+}
+
+// rewriteCSSURLs: Rewrite all url(...) in inline style
+func rewriteCSSURLs(style string, rewrite func(string) string) string {
+	return cssURLRegex.ReplaceAllStringFunc(style, func(match string) string {
+		groups := cssURLRegex.FindStringSubmatch(match)
+		link := groups[1] + groups[2] + groups[3]
+		if link == "" {
+			return match
+		}
+
+		rewritten := rewrite(link)
+		if rewritten == link {
+			return match // Keep original quoting
+		}
+
+		return `url("` + strings.ReplaceAll(rewritten, `"`, `%22`) + `")`
+	})
+}
+
+// rewriteSrcset: Rewrite each candidate of "url [descriptor], ..." list, URLs may contain commas
+func rewriteSrcset(srcset string, rewrite func(string) string) string {
+	var (
+		b          strings.Builder
+		candidates int
+	)
+	rest := srcset
+	for {
+		rest = strings.TrimLeft(rest, " \t\n\r\f,")
+		if rest == "" {
+			break
+		}
+
+		// URL runs until whitespace, trailing commas are separators
+		end := strings.IndexAny(rest, " \t\n\r\f")
+		if end < 0 {
+			end = len(rest)
+		}
+		link := rest[:end]
+		rest = rest[end:]
+		descriptor := ""
+		if trimmed := strings.TrimRight(link, ","); trimmed != link {
+			link = trimmed
+		} else {
+			// Descriptor runs until next comma
+			if comma := strings.IndexByte(rest, ','); comma >= 0 {
+				descriptor = strings.TrimSpace(rest[:comma])
+				rest = rest[comma+1:]
+			} else {
+				descriptor = strings.TrimSpace(rest)
+				rest = ""
+			}
+		}
+
+		if candidates > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(rewrite(link))
+		if descriptor != "" {
+			b.WriteString(" ")
+			b.WriteString(descriptor)
+		}
+		candidates++
+	}
+
+	return b.String()
+}
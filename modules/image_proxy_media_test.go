@@ -0,0 +1,92 @@
+package modules
+
+import (
+	"testing"
+)
+
+// rewriteForTest: Mimic ProcessLink, only absolute http(s) links are rewritten
+func rewriteForTest(src string) string {
+	if !isAbsoluteHTTPURL(src) {
+		return src
+	}
+
+	return "/p?s=" + src
+}
+
+func TestIsAbsoluteHTTPURL(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"https://example.com/a.jpg", true},
+		{"http://example.com/a.jpg", true},
+		{"HTTPS://example.com/a.jpg", true},
+		{"//example.com/a.jpg", false},
+		{"/a.jpg", false},
+		{"a.jpg", false},
+		{"data:image/png;base64,AAAA", false},
+		{"javascript:alert(1)", false},
+		{"https://", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			if got := isAbsoluteHTTPURL(tt.src); got != tt.want {
+				t.Errorf("isAbsoluteHTTPURL(%q) = %v, want %v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteSrcset(t *testing.T) {
+	tests := []struct {
+		name   string
+		srcset string
+		want   string
+	}{
+		{"single", "https://a.com/1.jpg", "/p?s=https://a.com/1.jpg"},
+		{"descriptors", "https://a.com/1.jpg 1x, https://a.com/2.jpg 2x", "/p?s=https://a.com/1.jpg 1x, /p?s=https://a.com/2.jpg 2x"},
+		{"width descriptors", " https://a.com/1.jpg 480w ,https://a.com/2.jpg 960w ", "/p?s=https://a.com/1.jpg 480w, /p?s=https://a.com/2.jpg 960w"},
+		{"comma in url", "https://a.com/img,w_100.jpg 1x, https://a.com/img,w_200.jpg 2x", "/p?s=https://a.com/img,w_100.jpg 1x, /p?s=https://a.com/img,w_200.jpg 2x"},
+		{"no descriptor separator", "https://a.com/1.jpg,https://a.com/2.jpg", "/p?s=https://a.com/1.jpg,https://a.com/2.jpg"},
+		{"trailing comma", "https://a.com/1.jpg, https://a.com/2.jpg 2x", "/p?s=https://a.com/1.jpg, /p?s=https://a.com/2.jpg 2x"},
+		{"relative kept", "/1.jpg 1x, https://a.com/2.jpg 2x", "/1.jpg 1x, /p?s=https://a.com/2.jpg 2x"},
+		{"data uri kept", "data:image/gif;base64,R0lGOD 1x", "data:image/gif;base64,R0lGOD 1x"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewriteSrcset(tt.srcset, rewriteForTest); got != tt.want {
+				t.Errorf("rewriteSrcset() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteCSSURLs(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		want  string
+	}{
+		{"unquoted", "background-image: url(https://a.com/1.jpg)", `background-image: url("/p?s=https://a.com/1.jpg")`},
+		{"double quoted", `background: url( "https://a.com/1.jpg" ) no-repeat`, `background: url("/p?s=https://a.com/1.jpg") no-repeat`},
+		{"single quoted", "background: url('https://a.com/1.jpg')", `background: url("/p?s=https://a.com/1.jpg")`},
+		{"multiple", "background: url(https://a.com/1.jpg), url(https://a.com/2.jpg)", `background: url("/p?s=https://a.com/1.jpg"), url("/p?s=https://a.com/2.jpg")`},
+		{"quote escaped", `background: url('https://a.com/"1".jpg')`, `background: url("/p?s=https://a.com/%221%22.jpg")`},
+		{"relative kept", "background: url('/1.jpg')", "background: url('/1.jpg')"},
+		{"data uri kept", "background: url(data:image/png;base64,AAAA)", "background: url(data:image/png;base64,AAAA)"},
+		{"empty kept", "background: url()", "background: url()"},
+		{"no url", "color: red", "color: red"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewriteCSSURLs(tt.style, rewriteForTest); got != tt.want {
+				t.Errorf("rewriteCSSURLs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package modules
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+)
+
+// hostRule: Get rule of the most specific (longest) matching host glob
+func (p *ImageProxy) hostRule(host string) (*types.ConfigImageProxyRule, bool) {
+	var (
+		matched string
+		found   bool
+	)
+	for pattern := range p.hosts {
+		if (!found || len(pattern) > len(matched) || (len(pattern) == len(matched) && pattern < matched)) && matchHost([]string{pattern}, host) {
+			matched = pattern
+			found = true
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	rule := p.hosts[matched]
+	return &rule, true
+}
+
+// applyRuleHeaders: Set request headers from rules in order, later ones take precedence
+func applyRuleHeaders(header http.Header, rules ...*types.ConfigImageProxyRule) {
+	cookies := make(map[string]string)
+	for _, rule := range rules {
+		if rule == nil {
+			continue
+		}
+
+		for key, value := range rule.Headers {
+			header.Set(key, value)
+		}
+		if rule.Origin != nil {
+			header.Set("Origin", *rule.Origin)
+		}
+		if rule.Referer != nil {
+			header.Set("Referer", *rule.Referer)
+		}
+		if rule.UserAgent != nil {
+			header.Set("User-Agent", *rule.UserAgent)
+		}
+		for name, value := range rule.Cookies {
+			cookies[name] = value
+		}
+	}
+
+	// Build cookie header in stable order
+	if len(cookies) > 0 {
+		names := make([]string, 0, len(cookies))
+		for name := range cookies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		pairs := make([]string, 0, len(names))
+		for _, name := range names {
+			pairs = append(pairs, (&http.Cookie{Name: name, Value: cookies[name]}).String())
+		}
+		header.Set("Cookie", strings.Join(pairs, "; "))
+	}
+}
+
+// shouldProxy: Check source against skip options, destination rules and include / exclude host patterns
+func (p *ImageProxy) shouldProxy(src string, base *url.URL, platform string) bool {
+	// Relative links, data: URIs and other schemes are kept untouched
+	if !isAbsoluteHTTPURL(src) {
+		return false
+	}
+
+	u, err := url.Parse(src)
+	if err != nil {
+		return false
+	}
+
+	// Proxy would refuse it anyway
+	if err = p.checkURL(u, platform); err != nil {
+		return false
+	}
+
+	if p.skipProxied && strings.EqualFold(u.Host, base.Host) && strings.HasPrefix(u.Path, strings.TrimSuffix(base.Path, "/")+p.path) {
+		return false
+	}
+
+	// Exclude by global or platform patterns
+	rule, hasRule := p.rules[platform]
+	if matchHost(p.exclude, u.Hostname()) || (hasRule && matchHost(rule.Exclude, u.Hostname())) {
+		return false
+	}
+
+	// Include by platform patterns if any, otherwise global ones
+	include := p.include
+	if hasRule && len(rule.Include) > 0 {
+		include = rule.Include
+	}
+
+	return len(include) == 0 || matchHost(include, u.Hostname())
+}
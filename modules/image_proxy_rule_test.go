@@ -0,0 +1,94 @@
+package modules
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+	"go.uber.org/zap"
+)
+
+func TestImageProxyShouldProxy(t *testing.T) {
+	p, err := NewImageProxy(&types.ConfigImageProxy{
+		Path:        "/img",
+		AllowHosts:  []string{"*.sinaimg.cn", "*.twimg.com", "*.example.com"},
+		Include:     []string{"*.sinaimg.cn", "*.example.com"},
+		Exclude:     []string{"static.example.com"},
+		SkipProxied: true,
+		Sign:        &types.ConfigImageProxySign{Keys: []string{"key"}},
+		Rules: map[string]types.ConfigImageProxyRule{
+			"twitter": {
+				AllowHosts: []string{"pbs.twimg.com"},
+				Include:    []string{"*.twimg.com"},
+				Exclude:    []string{"abs.twimg.com"},
+			},
+		},
+	}, nil, "", zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://proxy.example.com/prefix")
+
+	tests := []struct {
+		name     string
+		src      string
+		platform string
+		want     bool
+	}{
+		{"global include", "https://wx1.sinaimg.cn/a.jpg", "weibo", true},
+		{"not included", "https://pbs.twimg.com/a.jpg", "weibo", false},
+		{"global exclude", "https://static.example.com/a.jpg", "weibo", false},
+		{"not allowed globally", "https://evil.com/a.jpg", "weibo", false},
+		{"platform include", "https://pbs.twimg.com/a.jpg", "twitter", true},
+		{"platform include not allowed", "https://video.twimg.com/a.mp4", "twitter", false},
+		{"platform exclude", "https://abs.twimg.com/a.jpg", "twitter", false},
+		{"global exclude applies to platform", "https://static.example.com/a.jpg", "twitter", false},
+		{"already proxied", "https://proxy.example.com/prefix/img?s=x", "weibo", false},
+		{"same host other path", "https://proxy.example.com/a.jpg", "weibo", true},
+		{"relative", "/a.jpg", "weibo", false},
+		{"protocol relative", "//wx1.sinaimg.cn/a.jpg", "weibo", false},
+		{"data uri", "data:image/png;base64,AAAA", "weibo", false},
+		{"other scheme", "ftp://wx1.sinaimg.cn/a.jpg", "weibo", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.shouldProxy(tt.src, base, tt.platform); got != tt.want {
+				t.Errorf("shouldProxy(%q, %q) = %v, want %v", tt.src, tt.platform, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageProxyHostRule(t *testing.T) {
+	referer := func(s string) *string { return &s }
+	p := &ImageProxy{hosts: map[string]types.ConfigImageProxyRule{
+		"*.example.com":     {Referer: referer("wildcard")},
+		"img.example.com":   {Referer: referer("exact")},
+		"*.img.example.com": {Referer: referer("nested")},
+	}}
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"img.example.com", "exact"},
+		{"a.img.example.com", "nested"},
+		{"b.example.com", "wildcard"},
+		{"IMG.Example.com", "exact"},
+		{"example.org", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			rule, ok := p.hostRule(tt.host)
+			got := ""
+			if ok {
+				got = *rule.Referer
+			}
+			if got != tt.want {
+				t.Errorf("hostRule(%q) = %q, want %q", tt.host, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package modules
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+)
+
+func TestImageProxySignerVerify(t *testing.T) {
+	signer, err := newImageProxySigner(&types.ConfigImageProxySign{Keys: []string{"new", "old"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldSigner, err := newImageProxySigner(&types.ConfigImageProxySign{Keys: []string{"old"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSigner, err := newImageProxySigner(&types.ConfigImageProxySign{Keys: []string{"other"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiringSigner, err := newImageProxySigner(&types.ConfigImageProxySign{Keys: []string{"new"}, Expire: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, platform := "https://pbs.twimg.com/a.jpg", "twitter"
+	sig := signer.Sign(src, platform)
+	expiringSig := expiringSigner.Sign(src, platform)
+	expiredSig := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10) + "." + signer.mac([]byte("new"), src, platform, time.Now().Add(-time.Minute).Unix())
+
+	tests := []struct {
+		name     string
+		src      string
+		platform string
+		sig      string
+		expiring bool // Verify with expiring signer
+		ok       bool
+	}{
+		{"valid", src, platform, sig, false, true},
+		{"rotated key", src, platform, oldSigner.Sign(src, platform), false, true},
+		{"unknown key", src, platform, otherSigner.Sign(src, platform), false, false},
+		{"missing", src, platform, "", false, false},
+		{"tampered source", "https://evil.example.com/a.jpg", platform, sig, false, false},
+		{"tampered platform", src, "weibo", sig, false, false},
+		{"not expired", src, platform, expiringSig, true, true},
+		{"expiring accepted without expire", src, platform, expiringSig, false, true},
+		{"without expiry when expire is set", src, platform, sig, true, false},
+		{"expired", src, platform, expiredSig, false, false},
+		{"tampered expiry", src, platform, "9999999999." + strings.SplitN(expiringSig, ".", 2)[1], true, false},
+		{"malformed expiry", src, platform, "abc." + sig, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := signer
+			if tt.expiring {
+				verifier = expiringSigner
+			}
+			err := verifier.Verify(tt.src, tt.platform, tt.sig)
+			if (err == nil) != tt.ok {
+				t.Errorf("Verify() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestImageProxySignerStableWithinPeriod(t *testing.T) {
+	signer, err := newImageProxySigner(&types.ConfigImageProxySign{Keys: []string{"key"}, Expire: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a, b := signer.Sign("https://a/b.jpg", "x"), signer.Sign("https://a/b.jpg", "x"); a != b {
+		t.Errorf("Sign() not stable: %s != %s", a, b)
+	}
+}
+
+func TestNewImageProxySigner(t *testing.T) {
+	if _, err := newImageProxySigner(&types.ConfigImageProxySign{}); err == nil {
+		t.Error("newImageProxySigner() without keys should fail")
+	}
+	if _, err := newImageProxySigner(&types.ConfigImageProxySign{Keys: []string{""}}); err == nil {
+		t.Error("newImageProxySigner() with empty key should fail")
+	}
+}
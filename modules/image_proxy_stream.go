@@ -0,0 +1,56 @@
+package modules
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Request headers forwarded to upstream
+var imageProxyForwardHeaders = []string{"Range", "If-Range"}
+
+// Response headers passed through to client
+var imageProxyPassHeaders = []string{"Content-Length", "Content-Range", "Accept-Ranges", "Last-Modified", "ETag", "Cache-Control"}
+
+var errImageTooLarge = errors.New("object exceeds max size")
+
+// responseObjectSize: Full object size from Content-Range or Content-Length, -1 if unknown
+func responseObjectSize(res *http.Response) int64 {
+	if contentRange := res.Header.Get("Content-Range"); contentRange != "" {
+		if i := strings.LastIndexByte(contentRange, '/'); i >= 0 {
+			if size, err := strconv.ParseInt(contentRange[i+1:], 10, 64); err == nil {
+				return size
+			}
+		}
+	}
+
+	return res.ContentLength
+}
+
+// maxBytesReader: Fail when more than remaining bytes are read, for bodies without known length
+type maxBytesReader struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (r *maxBytesReader) Read(b []byte) (int, error) {
+	if r.remaining <= 0 {
+		// Check if there is anything left
+		var probe [1]byte
+		n, err := r.ReadCloser.Read(probe[:])
+		if n > 0 {
+			return 0, errImageTooLarge
+		}
+		return 0, err
+	}
+
+	if int64(len(b)) > r.remaining {
+		b = b[:r.remaining]
+	}
+	n, err := r.ReadCloser.Read(b)
+	r.remaining -= int64(n)
+
+	return n, err
+}
@@ -0,0 +1,206 @@
+package modules
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// newTestUpstream: Serve a PNG image and a video, recording Range header of each request
+func newTestUpstream(t *testing.T) (*httptest.Server, func() []string) {
+	var imageBody bytes.Buffer
+	if err := png.Encode(&imageBody, image.NewRGBA(image.Rect(0, 0, 64, 32))); err != nil {
+		t.Fatal(err)
+	}
+	videoBody := bytes.Repeat([]byte("0123456789"), 100)
+
+	var (
+		lock   sync.Mutex
+		ranges []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		lock.Unlock()
+
+		switch r.URL.Path {
+		case "/a.png":
+			w.Header().Set("Content-Type", "image/png")
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(imageBody.Bytes()))
+		case "/v.mp4":
+			w.Header().Set("Content-Type", "video/mp4")
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(videoBody))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), ranges...)
+	}
+}
+
+func TestImageProxyRange(t *testing.T) {
+	upstream, upstreamRanges := newTestUpstream(t)
+
+	p, err := NewImageProxy(&types.ConfigImageProxy{
+		Path:         "/img",
+		AllowPrivate: true,
+		Rules: map[string]types.ConfigImageProxyRule{
+			"p": {Transform: &types.ConfigImageTransform{Width: 16}},
+		},
+	}, nil, "", zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		src            string
+		wantStatus     int
+		wantType       string
+		wantUpstream   []string
+		wantTransform  bool
+		wantBodyLength int
+	}{
+		{"video with platform transform", "/v.mp4", http.StatusPartialContent, "video/mp4", []string{"bytes=0-9"}, false, 10},
+		{"transformed image", "/a.png", http.StatusPartialContent, "image/png", []string{"bytes=0-9", ""}, true, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(upstreamRanges())
+
+			query := url.Values{"s": {upstream.URL + tt.src}, "p": {"p"}}
+			req := httptest.NewRequest(http.MethodGet, "/img?"+query.Encode(), nil)
+			req.Header.Set("Range", "bytes=0-9")
+			rec := httptest.NewRecorder()
+			if err := p.Proxy(echo.New().NewContext(req, rec)); err != nil {
+				t.Fatal(err)
+			}
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("content type = %s, want %s", got, tt.wantType)
+			}
+			if rec.Body.Len() != tt.wantBodyLength {
+				t.Errorf("body length = %d, want %d", rec.Body.Len(), tt.wantBodyLength)
+			}
+			if got := upstreamRanges()[before:]; strings.Join(got, "|") != strings.Join(tt.wantUpstream, "|") {
+				t.Errorf("upstream ranges = %q, want %q", got, tt.wantUpstream)
+			}
+
+			// Transformed image is ranged over the result, not the source
+			contentRange := rec.Header().Get("Content-Range")
+			if tt.wantTransform && strings.HasSuffix(contentRange, "/"+upstreamLength(t, upstream.URL+tt.src)) {
+				t.Errorf("content range %s is over source", contentRange)
+			}
+		})
+	}
+}
+
+func upstreamLength(t *testing.T, src string) string {
+	res, err := http.Head(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	return res.Header.Get("Content-Length")
+}
+
+func TestImageProxyBaseURL(t *testing.T) {
+	tests := []struct {
+		name           string
+		baseURL        string
+		trustForwarded bool
+		tls            bool
+		header         map[string]string
+		want           string
+	}{
+		{"request host", "", false, false, nil, "http://rss.example.com"},
+		{"tls", "", false, true, nil, "https://rss.example.com"},
+		{"untrusted forwarded", "", false, false, map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.com"}, "http://rss.example.com"},
+		{"trusted forwarded", "", true, false, map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "public.example.com"}, "https://public.example.com"},
+		{"outermost forwarded", "", true, false, map[string]string{"X-Forwarded-Proto": "HTTPS, http", "X-Forwarded-Host": "public.example.com, internal"}, "https://public.example.com"},
+		{"invalid forwarded proto", "", true, true, map[string]string{"X-Forwarded-Proto": "ftp"}, "https://rss.example.com"},
+		{"configured", "https://img.example.com/sub", true, false, map[string]string{"X-Forwarded-Host": "public.example.com"}, "https://img.example.com/sub"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewImageProxy(&types.ConfigImageProxy{
+				Path:           "/img",
+				BaseURL:        tt.baseURL,
+				TrustForwarded: tt.trustForwarded,
+			}, nil, "", zap.NewNop())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			scheme := "http"
+			if tt.tls {
+				scheme = "https"
+			}
+			req := httptest.NewRequest(http.MethodGet, scheme+"://rss.example.com/twitter/user/x", nil)
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+
+			if got := p.BaseURL(req).String(); got != tt.want {
+				t.Errorf("BaseURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewImageProxyBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"img.example.com", "/img", "ftp://img.example.com"} {
+		if _, err := NewImageProxy(&types.ConfigImageProxy{Path: "/img", BaseURL: baseURL}, nil, "", zap.NewNop()); err == nil {
+			t.Errorf("NewImageProxy() with base url %s should fail", baseURL)
+		}
+	}
+}
+
+func TestImageProxyProcessLink(t *testing.T) {
+	base, _ := url.Parse("https://img.example.com/sub/")
+	src := "https://pbs.twimg.com/a.jpg"
+
+	tests := []struct {
+		name    string
+		compact bool
+		want    string
+	}{
+		{"query", false, "https://img.example.com/sub/img?p=twitter&s=https%3A%2F%2Fpbs.twimg.com%2Fa.jpg"},
+		{"compact", true, "https://img.example.com/sub/img/twitter/aHR0cHM6Ly9wYnMudHdpbWcuY29tL2EuanBn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewImageProxy(&types.ConfigImageProxy{Path: "/img", Compact: tt.compact}, nil, "", zap.NewNop())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := p.ProcessLink(src, base, "twitter"); got != tt.want {
+				t.Errorf("ProcessLink() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package modules
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Register decoder, so that GIFs are detected and kept
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Register decoder
+)
+
+const (
+	ImageFitContain = "contain" // Scale down to fit in box, keep aspect ratio
+	ImageFitCover   = "cover"   // Scale to cover box and crop center, keep aspect ratio
+	ImageFitFill    = "fill"    // Stretch to box
+
+	ImageFormatJPEG = "jpeg"
+	ImageFormatPNG  = "png"
+	ImageFormatWebP = "webp"
+)
+
+const (
+	imageTransformMaxDimension     = 4096
+	imageTransformMaxPixels        = 50_000_000
+	imageTransformDefaultQuality   = 85
+	imageTransformDefaultMaxSource = 20 << 20 // 20 MiB
+)
+
+type imageTransform struct {
+	width   int
+	height  int
+	fit     string
+	quality int
+	format  string
+}
+
+// parseImageTransform: Merge transform query parameters over platform defaults, nil if nothing to do
+func parseImageTransform(query url.Values, defaults *types.ConfigImageTransform) (*imageTransform, error) {
+	t := &imageTransform{}
+	if defaults != nil {
+		t.width = defaults.Width
+		t.height = defaults.Height
+		t.fit = defaults.Fit
+		t.quality = defaults.Quality
+		t.format = defaults.Format
+	}
+
+	// Parse query
+	for param, target := range map[string]*int{"w": &t.width, "h": &t.height, "q": &t.quality} {
+		if value := query.Get(param); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", param, err)
+			}
+			*target = parsed
+		}
+	}
+	if fit := query.Get("fit"); fit != "" {
+		t.fit = fit
+	}
+	if format := query.Get("f"); format != "" {
+		t.format = format
+	}
+
+	// Validate
+	if t.width < 0 || t.width > imageTransformMaxDimension || t.height < 0 || t.height > imageTransformMaxDimension {
+		return nil, fmt.Errorf("invalid size %dx%d", t.width, t.height)
+	}
+	if t.quality < 0 || t.quality > 100 {
+		return nil, fmt.Errorf("invalid quality %d", t.quality)
+	}
+	t.format = strings.ToLower(t.format)
+	if t.format == "jpg" {
+		t.format = ImageFormatJPEG
+	}
+	switch t.format {
+	case "", ImageFormatJPEG, ImageFormatPNG, ImageFormatWebP:
+	default:
+		return nil, fmt.Errorf("unsupported format %s", t.format)
+	}
+	switch t.fit {
+	case "":
+		t.fit = ImageFitContain
+	case ImageFitContain, ImageFitCover, ImageFitFill:
+	default:
+		return nil, fmt.Errorf("unsupported fit %s", t.fit)
+	}
+
+	if t.width == 0 && t.height == 0 && t.format == "" && t.quality == 0 {
+		return nil, nil
+	}
+
+	return t, nil
+}
+
+// String: Variant name used in cache key
+func (t *imageTransform) String() string {
+	if t == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("w=%d,h=%d,fit=%s,q=%d,f=%s", t.width, t.height, t.fit, t.quality, t.format)
+}
+
+// Apply: Resize and convert image, original is returned untouched when not decodable or no change is needed
+func (t *imageTransform) Apply(body []byte, contentType string) ([]byte, string, error) {
+	cfg, sourceFormat, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return body, contentType, fmt.Errorf("failed to decode image config: %w", err)
+	}
+	if sourceFormat == "gif" {
+		return body, contentType, nil // Keep animation
+	}
+	if cfg.Width*cfg.Height > imageTransformMaxPixels {
+		return body, contentType, fmt.Errorf("image too large: %dx%d", cfg.Width, cfg.Height)
+	}
+
+	// Calculate target size and source crop
+	dstW, dstH, crop := t.layout(cfg.Width, cfg.Height)
+	resize := dstW != cfg.Width || dstH != cfg.Height || crop.Dx() != cfg.Width || crop.Dy() != cfg.Height
+
+	// Choose output format, WebP encoding is not available so fallback to JPEG or PNG
+	format := t.format
+	if format == "" {
+		format = sourceFormat
+	}
+	if !resize && format == sourceFormat && t.quality == 0 {
+		return body, contentType, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return body, contentType, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var dst image.Image = src
+	if resize {
+		scaled := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+		draw.BiLinear.Scale(scaled, scaled.Bounds(), src, crop.Add(src.Bounds().Min), draw.Src, nil)
+		dst = scaled
+	}
+
+	if format != ImageFormatJPEG && format != ImageFormatPNG {
+		if isOpaque(dst) {
+			format = ImageFormatJPEG
+		} else {
+			format = ImageFormatPNG
+		}
+	}
+
+	// Encode
+	var b bytes.Buffer
+	switch format {
+	case ImageFormatJPEG:
+		quality := t.quality
+		if quality == 0 {
+			quality = imageTransformDefaultQuality
+		}
+		if !isOpaque(dst) {
+			dst = flatten(dst)
+		}
+		err = jpeg.Encode(&b, dst, &jpeg.Options{Quality: quality})
+	default:
+		err = png.Encode(&b, dst)
+	}
+	if err != nil {
+		return body, contentType, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	// Keep original if conversion only made it larger
+	if !resize && format == sourceFormat && b.Len() >= len(body) {
+		return body, contentType, nil
+	}
+
+	return b.Bytes(), "image/" + format, nil
+}
+
+// layout: Get destination size and source crop rectangle (relative to origin) by fit mode, never upscale
+func (t *imageTransform) layout(srcW int, srcH int) (int, int, image.Rectangle) {
+	full := image.Rect(0, 0, srcW, srcH)
+	w, h := t.width, t.height
+
+	switch {
+	case w == 0 && h == 0:
+		return srcW, srcH, full
+	case w == 0:
+		w = scaleDim(srcW, h, srcH)
+	case h == 0:
+		h = scaleDim(srcH, w, srcW)
+	}
+
+	switch t.fit {
+	case ImageFitFill:
+		return min(w, srcW), min(h, srcH), full
+	case ImageFitCover:
+		// Scale by larger ratio, then crop center of source to box aspect
+		if srcW*h > srcH*w {
+			cropW := scaleDim(w, srcH, h)
+			x := (srcW - cropW) / 2
+			full = image.Rect(x, 0, x+cropW, srcH)
+		} else {
+			cropH := scaleDim(h, srcW, w)
+			y := (srcH - cropH) / 2
+			full = image.Rect(0, y, srcW, y+cropH)
+		}
+		if w > full.Dx() || h > full.Dy() {
+			return full.Dx(), full.Dy(), full
+		}
+		return w, h, full
+	default:
+		// Contain: scale by smaller ratio
+		if w >= srcW && h >= srcH {
+			return srcW, srcH, full
+		}
+		if srcW*h > srcH*w {
+			return w, max(scaleDim(srcH, w, srcW), 1), full
+		}
+		return max(scaleDim(srcW, h, srcH), 1), h, full
+	}
+}
+
+// scaleDim: value * num / den, rounded
+func scaleDim(value int, num int, den int) int {
+	return (value*num + den/2) / den
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+
+	return false
+}
+
+// flatten: Draw image over white background for formats without alpha
+func flatten(img image.Image) image.Image {
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+
+	return dst
+}
@@ -0,0 +1,143 @@
+package modules
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"net/url"
+	"testing"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+)
+
+func TestParseImageTransform(t *testing.T) {
+	defaults := &types.ConfigImageTransform{Width: 1280}
+
+	tests := []struct {
+		name     string
+		query    string
+		defaults *types.ConfigImageTransform
+		want     string
+		wantErr  bool
+	}{
+		{"nothing", "", nil, "", false},
+		{"defaults", "", defaults, "w=1280,h=0,fit=contain,q=0,f=", false},
+		{"query over defaults", "w=640&h=480&fit=cover", defaults, "w=640,h=480,fit=cover,q=0,f=", false},
+		{"jpg alias", "f=JPG&q=80", nil, "w=0,h=0,fit=contain,q=80,f=jpeg", false},
+		{"fit only", "fit=cover", nil, "", false},
+		{"invalid width", "w=abc", nil, "", true},
+		{"too large", "w=5000", nil, "", true},
+		{"negative", "h=-1", nil, "", true},
+		{"invalid quality", "q=101", nil, "", true},
+		{"unsupported format", "f=gif", nil, "", true},
+		{"unsupported fit", "fit=stretch", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			transform, err := parseImageTransform(query, tt.defaults)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseImageTransform() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := transform.String(); got != tt.want {
+				t.Errorf("parseImageTransform() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageTransformLayout(t *testing.T) {
+	tests := []struct {
+		name   string
+		t      imageTransform
+		srcW   int
+		srcH   int
+		wantW  int
+		wantH  int
+		wantCr image.Rectangle
+	}{
+		{"no size", imageTransform{fit: ImageFitContain}, 800, 600, 800, 600, image.Rect(0, 0, 800, 600)},
+		{"contain width", imageTransform{width: 400, fit: ImageFitContain}, 800, 600, 400, 300, image.Rect(0, 0, 800, 600)},
+		{"contain height", imageTransform{height: 300, fit: ImageFitContain}, 800, 600, 400, 300, image.Rect(0, 0, 800, 600)},
+		{"contain box", imageTransform{width: 400, height: 400, fit: ImageFitContain}, 800, 600, 400, 300, image.Rect(0, 0, 800, 600)},
+		{"contain never upscale", imageTransform{width: 1600, fit: ImageFitContain}, 800, 600, 800, 600, image.Rect(0, 0, 800, 600)},
+		{"cover wide source", imageTransform{width: 300, height: 300, fit: ImageFitCover}, 800, 600, 300, 300, image.Rect(100, 0, 700, 600)},
+		{"cover tall source", imageTransform{width: 300, height: 300, fit: ImageFitCover}, 600, 800, 300, 300, image.Rect(0, 100, 600, 700)},
+		{"cover never upscale", imageTransform{width: 1000, height: 1000, fit: ImageFitCover}, 800, 600, 600, 600, image.Rect(100, 0, 700, 600)},
+		{"fill", imageTransform{width: 400, height: 100, fit: ImageFitFill}, 800, 600, 400, 100, image.Rect(0, 0, 800, 600)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h, crop := tt.t.layout(tt.srcW, tt.srcH)
+			if w != tt.wantW || h != tt.wantH || crop != tt.wantCr {
+				t.Errorf("layout() = %d, %d, %v, want %d, %d, %v", w, h, crop, tt.wantW, tt.wantH, tt.wantCr)
+			}
+		})
+	}
+}
+
+func TestImageTransformApply(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 32; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 8), B: 128, A: 255})
+		}
+	}
+	var pngBody bytes.Buffer
+	if err := png.Encode(&pngBody, img); err != nil {
+		t.Fatal(err)
+	}
+	var gifBody bytes.Buffer
+	if err := gif.Encode(&gifBody, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		t               imageTransform
+		body            []byte
+		contentType     string
+		wantContentType string
+		wantW           int
+		wantH           int
+		wantUntouched   bool
+	}{
+		{"resize", imageTransform{width: 32, fit: ImageFitContain}, pngBody.Bytes(), "image/png", "image/png", 32, 16, false},
+		{"convert", imageTransform{format: ImageFormatJPEG, fit: ImageFitContain}, pngBody.Bytes(), "image/png", "image/jpeg", 64, 32, false},
+		{"webp output falls back", imageTransform{width: 16, format: ImageFormatWebP, fit: ImageFitContain}, pngBody.Bytes(), "image/png", "image/jpeg", 16, 8, false},
+		{"gif kept", imageTransform{width: 32, fit: ImageFitContain}, gifBody.Bytes(), "image/gif", "image/gif", 64, 32, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType, err := tt.t.Apply(tt.body, tt.contentType)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if contentType != tt.wantContentType {
+				t.Errorf("Apply() content type = %s, want %s", contentType, tt.wantContentType)
+			}
+			if tt.wantUntouched && !bytes.Equal(body, tt.body) {
+				t.Error("Apply() changed body")
+			}
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Width != tt.wantW || cfg.Height != tt.wantH {
+				t.Errorf("Apply() size = %dx%d, want %dx%d", cfg.Width, cfg.Height, tt.wantW, tt.wantH)
+			}
+		})
+	}
+
+	if _, _, err := (&imageTransform{width: 1}).Apply([]byte("not an image"), "image/png"); err == nil {
+		t.Error("Apply() on invalid image should fail")
+	}
+}
@@ -0,0 +1,80 @@
+package translate
+
+import (
+	"errors"
+	"unicode"
+)
+
+var ErrDetectUnsupported = errors.New("provider does not support language detection")
+
+// DetectMinConfidence: Minimum confidence of local detectors when not configured, guesses on short texts are below it
+const DetectMinConfidence = 0.3
+
+// DetectScript: Detect language by unicode script of letters, which works offline
+// but only for languages with a (almost) unique script. Returns empty language if not sure,
+// shared scripts are left to DetectNgram.
+func DetectScript(text string) (string, float64) {
+	var (
+		letters  int
+		han      int
+		kana     int
+		hangul   int
+		greek    int
+		thai     int
+		armenian int
+		georgian int
+	)
+
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+
+		letters++
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Greek, r):
+			greek++
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		case unicode.Is(unicode.Armenian, r):
+			armenian++
+		case unicode.Is(unicode.Georgian, r):
+			georgian++
+		}
+	}
+
+	if letters == 0 {
+		return "", 0
+	}
+
+	ratio := func(n int) float64 {
+		return float64(n) / float64(letters)
+	}
+
+	// Japanese mixes kana with han, so check kana first
+	switch {
+	case kana > 0 && ratio(kana+han) > 0.5 && ratio(kana) > 0.1:
+		return "ja", ratio(kana + han)
+	case ratio(hangul+han) > 0.5 && hangul > han:
+		return "ko", ratio(hangul + han)
+	case ratio(han) > 0.5:
+		return "zh", ratio(han)
+	case ratio(greek) > 0.5:
+		return "el", ratio(greek)
+	case ratio(thai) > 0.5:
+		return "th", ratio(thai)
+	case ratio(armenian) > 0.5:
+		return "hy", ratio(armenian)
+	case ratio(georgian) > 0.5:
+		return "ka", ratio(georgian)
+	}
+
+	// Latin, Cyrillic, Arabic, Hebrew... are shared by several languages
+	return "", 0
+}
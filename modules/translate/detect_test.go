@@ -0,0 +1,67 @@
+package translate
+
+import "testing"
+
+func TestDetectScript(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"今日はいい天気ですね", "ja"},
+		{"오늘은 날씨가 좋네요", "ko"},
+		{"今天天气很好", "zh"},
+		{"Καλημέρα κόσμε", "el"},
+		{"สวัสดีครับ", "th"},
+		{"The weather is nice today", ""},
+		{"Сегодня хорошая погода", ""},
+		{"12345 !!!", ""},
+	}
+
+	for _, tt := range tests {
+		if got, _ := DetectScript(tt.text); got != tt.want {
+			t.Errorf("DetectScript(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDetectNgram(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"The quick brown fox jumps over the lazy dog while the children are playing in the garden.", "en"},
+		{"Der schnelle braune Fuchs springt über den faulen Hund, während die Kinder im Garten spielen.", "de"},
+		{"Le renard brun rapide saute par-dessus le chien paresseux pendant que les enfants jouent dans le jardin.", "fr"},
+		{"El rápido zorro marrón salta sobre el perro perezoso mientras los niños juegan en el jardín.", "es"},
+		{"Быстрая коричневая лиса прыгает через ленивую собаку, пока дети играют в саду.", "ru"},
+		{"Швидка бура лисиця стрибає через ледачого пса, поки діти граються в саду.", "uk"},
+		{"الثعلب البني السريع يقفز فوق الكلب الكسول بينما يلعب الأطفال في الحديقة", "ar"},
+		{"今天天气很好", ""}, // Not covered by profiles
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got, confidence := DetectNgram(tt.text)
+		if got != tt.want {
+			t.Errorf("DetectNgram(%q) = %q (%.2f), want %q", tt.text, got, confidence, tt.want)
+		}
+		if tt.want != "" && (confidence < DetectMinConfidence || confidence > 1) {
+			t.Errorf("DetectNgram(%q) confidence = %f, want [%.2f, 1]", tt.text, confidence, DetectMinConfidence)
+		}
+	}
+}
+
+func TestDetectNgramShortText(t *testing.T) {
+	// Guesses on short texts must not be trusted
+	for _, text := range []string{
+		"Hello",
+		"Breaking news today",
+		"GitHub Trending",
+		"Fix login bug",
+		"Новости",
+	} {
+		if got, confidence := DetectNgram(text); confidence >= DetectMinConfidence {
+			t.Errorf("DetectNgram(%q) = %q (%.2f), want confidence below %.2f", text, got, confidence, DetectMinConfidence)
+		}
+	}
+}
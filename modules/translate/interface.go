@@ -1,5 +1,27 @@
 package translate
 
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoTranslation is returned by providers which have no translation for given text,
+// so that next provider in chain can be tried
+var ErrNoTranslation = errors.New("no translation found")
+
 type Provider interface {
-	Translate(string, string, bool) (*string, error)
+	// Translate(ctx, src, sourceLang, targetLang, isHTML), sourceLang is empty for auto detect
+	Translate(context.Context, string, string, string, bool) (*string, error)
+}
+
+// Detector is optionally implemented by providers which can detect language of text
+type Detector interface {
+	// Detect(ctx, text) returns language code and confidence (0 ~ 1)
+	Detect(context.Context, string) (string, float64, error)
+}
+
+// BatchProvider is optionally implemented by providers which can translate multiple texts in one request
+type BatchProvider interface {
+	// TranslateBatch(ctx, srcs, sourceLang, targetLang, isHTML) returns translated texts in same order
+	TranslateBatch(context.Context, []string, string, string, bool) ([]string, error)
 }
@@ -0,0 +1,224 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+var (
+	_ Provider      = (*Limited)(nil)
+	_ Detector      = (*Limited)(nil)
+	_ BatchProvider = (*Limited)(nil)
+)
+
+// Limited wraps a provider with a concurrency semaphore and a rate limiter,
+// shared by all requests that use this provider.
+type Limited struct {
+	p Provider
+	l *zap.Logger
+
+	sem chan struct{}
+	rl  *rate.Limiter
+
+	statsLock sync.Mutex
+	stats     LimitStats
+}
+
+type LimitStats struct {
+	Waiting   int64   `json:"waiting"`
+	Running   int64   `json:"running"`
+	Acquired  uint64  `json:"acquired"`
+	Expired   uint64  `json:"expired"` // Gave up before a slot is available
+	TotalWait float64 `json:"total_wait_ms"`
+	MaxWait   float64 `json:"max_wait_ms"`
+	AvgWait   float64 `json:"avg_wait_ms"`
+}
+
+func NewLimited(p Provider, cfg *types.ConfigTranslateLimit, l *zap.Logger) *Limited {
+	lp := &Limited{
+		p: p,
+		l: l,
+	}
+
+	if cfg.MaxConcurrency > 0 {
+		lp.sem = make(chan struct{}, cfg.MaxConcurrency)
+	}
+
+	if cfg.RateLimit > 0 {
+		burst := cfg.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		lp.rl = rate.NewLimiter(rate.Limit(cfg.RateLimit), burst)
+	}
+
+	return lp
+}
+
+func (lp *Limited) Translate(ctx context.Context, src string, sourceLang string, targetLang string, isHTML bool) (*string, error) {
+	err := lp.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer lp.release()
+
+	// Execute with real provider
+	return lp.p.Translate(ctx, src, sourceLang, targetLang, isHTML)
+}
+
+func (lp *Limited) TranslateBatch(ctx context.Context, srcs []string, sourceLang string, targetLang string, isHTML bool) ([]string, error) {
+	bp, ok := lp.p.(BatchProvider)
+	if !ok {
+		// Translate one by one simultaneously
+		return lp.translateEach(ctx, srcs, sourceLang, targetLang, isHTML)
+	}
+
+	err := lp.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer lp.release()
+
+	// Execute with real provider
+	return bp.TranslateBatch(ctx, srcs, sourceLang, targetLang, isHTML)
+}
+
+func (lp *Limited) translateEach(ctx context.Context, srcs []string, sourceLang string, targetLang string, isHTML bool) ([]string, error) {
+	var (
+		wg       sync.WaitGroup
+		errLock  sync.Mutex
+		firstErr error
+	)
+
+	results := make([]string, len(srcs))
+	for i, src := range srcs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			translated, err := lp.Translate(ctx, src, sourceLang, targetLang, isHTML)
+			if err != nil {
+				errLock.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				errLock.Unlock()
+				return
+			}
+			results[i] = *translated
+		}()
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return results, nil
+}
+
+func (lp *Limited) Detect(ctx context.Context, text string) (string, float64, error) {
+	d, ok := lp.p.(Detector)
+	if !ok {
+		return "", 0, ErrDetectUnsupported
+	}
+
+	err := lp.wait(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+
+	defer lp.release()
+
+	// Execute with real provider
+	return d.Detect(ctx, text)
+}
+
+// wait: Wait in queue till a slot is acquired
+func (lp *Limited) wait(ctx context.Context) error {
+	start := time.Now()
+	lp.updateStats(func(s *LimitStats) { s.Waiting++ })
+	err := lp.acquire(ctx)
+	wait := time.Since(start)
+	if err != nil {
+		lp.updateStats(func(s *LimitStats) {
+			s.Waiting--
+			s.Expired++
+		})
+		return fmt.Errorf("failed to acquire translate slot after %s: %w", wait, err)
+	}
+
+	lp.l.Debug("translate slot acquired", zap.Duration("wait", wait))
+	lp.updateStats(func(s *LimitStats) {
+		s.Waiting--
+		s.Running++
+		s.Acquired++
+
+		waitMs := float64(wait) / float64(time.Millisecond)
+		s.TotalWait += waitMs
+		if waitMs > s.MaxWait {
+			s.MaxWait = waitMs
+		}
+	})
+
+	return nil
+}
+
+func (lp *Limited) acquire(ctx context.Context) error {
+	// Concurrency limit
+	if lp.sem != nil {
+		select {
+		case lp.sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// Rate limit
+	if lp.rl != nil {
+		err := lp.rl.Wait(ctx)
+		if err != nil {
+			// Return the slot we just took
+			if lp.sem != nil {
+				<-lp.sem
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (lp *Limited) release() {
+	if lp.sem != nil {
+		<-lp.sem
+	}
+	lp.updateStats(func(s *LimitStats) { s.Running-- })
+}
+
+func (lp *Limited) updateStats(update func(s *LimitStats)) {
+	lp.statsLock.Lock()
+	defer lp.statsLock.Unlock()
+	update(&lp.stats)
+}
+
+// Stats returns a snapshot of queue metrics
+func (lp *Limited) Stats() LimitStats {
+	lp.statsLock.Lock()
+	defer lp.statsLock.Unlock()
+
+	stats := lp.stats
+	if stats.Acquired > 0 {
+		stats.AvgWait = stats.TotalWait / float64(stats.Acquired)
+	}
+
+	return stats
+}
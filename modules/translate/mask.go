@@ -0,0 +1,297 @@
+package translate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	placeholderPattern = regexp.MustCompile(`\{\{\s*(\d+)\s*\}\}`)
+	htmlTagPattern     = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Elements masked as a whole in HTML
+var protectedElements = map[string]bool{
+	"pre":    true,
+	"code":   true,
+	"script": true,
+	"style":  true,
+}
+
+// Escape text content only, quotes are fine outside attributes
+var escapeHTMLText = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
+
+// Masker replaces protected patterns and glossary terms with placeholders before translation,
+// and restores them afterwards.
+type Masker struct {
+	protect      []*regexp.Regexp
+	glossary     map[string][]glossaryTerm // By target language
+	fingerprints map[string]string         // By target language, "" for languages without glossary
+}
+
+type glossaryTerm struct {
+	pattern     *regexp.Regexp
+	translation string
+}
+
+func NewMasker(protect []string, glossary map[string]map[string]string) (*Masker, error) {
+	m := &Masker{
+		glossary:     make(map[string][]glossaryTerm),
+		fingerprints: make(map[string]string),
+	}
+
+	// Compile protect patterns
+	for _, p := range protect {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile protect pattern %s: %w", p, err)
+		}
+		m.protect = append(m.protect, re)
+	}
+
+	// Compile glossary terms
+	for lang, terms := range glossary {
+		// Longer terms first to prevent partial match
+		sortedTerms := make([]string, 0, len(terms))
+		for term := range terms {
+			if term != "" {
+				sortedTerms = append(sortedTerms, term)
+			}
+		}
+		sort.Slice(sortedTerms, func(i, j int) bool {
+			return len(sortedTerms[i]) > len(sortedTerms[j])
+		})
+
+		for _, term := range sortedTerms {
+			m.glossary[lang] = append(m.glossary[lang], glossaryTerm{
+				pattern:     termPattern(term),
+				translation: terms[term],
+			})
+		}
+	}
+
+	// Fingerprint rules, so that results masked differently are cached apart
+	m.fingerprints[""] = maskFingerprint(protect, nil)
+	for lang, terms := range glossary {
+		m.fingerprints[lang] = maskFingerprint(protect, terms)
+	}
+
+	return m, nil
+}
+
+func maskFingerprint(protect []string, terms map[string]string) string {
+	if len(protect) == 0 && len(terms) == 0 {
+		return ""
+	}
+
+	h := sha256.New()
+	for _, p := range protect {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte{1})
+
+	sortedTerms := make([]string, 0, len(terms))
+	for term := range terms {
+		sortedTerms = append(sortedTerms, term)
+	}
+	sort.Strings(sortedTerms)
+	for _, term := range sortedTerms {
+		h.Write([]byte(term))
+		h.Write([]byte{0})
+		h.Write([]byte(terms[term]))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// termPattern: Match term with word boundaries where possible
+func termPattern(term string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(term)
+
+	first, _ := utf8.DecodeRuneInString(term)
+	if first < utf8.RuneSelf && (unicode.IsLetter(first) || unicode.IsDigit(first)) {
+		pattern = `\b` + pattern
+	}
+
+	last, _ := utf8.DecodeLastRuneInString(term)
+	if last < utf8.RuneSelf && (unicode.IsLetter(last) || unicode.IsDigit(last)) {
+		pattern = pattern + `\b`
+	}
+
+	return regexp.MustCompile(pattern)
+}
+
+// Mask: Replace protected parts and glossary terms with placeholders, returns masked text and replacements.
+// For HTML, code blocks are protected as a whole, and patterns are only matched in text between tags
+// (with entities decoded), so that tags, attributes and entities are never touched.
+func (m *Masker) Mask(src string, targetLang string, isHTML bool) (string, []string) {
+	var replacements []string
+	placeholder := func(replacement string) string {
+		replacements = append(replacements, replacement)
+		return fmt.Sprintf("{{%d}}", len(replacements)-1)
+	}
+
+	// Placeholder-like text in source is masked as well, so that it's never taken as a placeholder
+	escapePlaceholders := func(text string, escape func(string) string) string {
+		return placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+			return placeholder(escape(match))
+		})
+	}
+
+	terms := m.glossary[targetLang]
+	maskText := func(text string, escape func(string) string) string {
+		text = escapePlaceholders(text, escape)
+
+		// Protected parts are kept as-is
+		for _, re := range m.protect {
+			text = replaceOutsidePlaceholders(text, re, func(match string) string {
+				return placeholder(escape(match))
+			})
+		}
+
+		// Glossary terms are replaced with fixed translations
+		for _, term := range terms {
+			translation := escape(term.translation)
+			text = replaceOutsidePlaceholders(text, term.pattern, func(string) string {
+				return placeholder(translation)
+			})
+		}
+
+		return text
+	}
+
+	if !isHTML {
+		return maskText(src, func(s string) string { return s }), replacements
+	}
+
+	var sb strings.Builder
+	maskHTMLText := func(text string) {
+		sb.WriteString(escapeHTMLText(maskText(html.UnescapeString(text), escapeHTMLText)))
+	}
+
+	var (
+		last       int
+		blockTag   string // Name of protected element being skipped
+		blockDepth int
+		blockStart int
+	)
+	for _, loc := range htmlTagPattern.FindAllStringIndex(src, -1) {
+		tag := src[loc[0]:loc[1]]
+		name, closing, selfClosing := parseHTMLTag(tag)
+
+		// Inside protected element, find its end
+		if blockTag != "" {
+			if name == blockTag && closing {
+				blockDepth--
+				if blockDepth == 0 {
+					sb.WriteString(placeholder(src[blockStart:loc[1]]))
+					blockTag = ""
+					last = loc[1]
+				}
+			} else if name == blockTag && !selfClosing {
+				blockDepth++
+			}
+			continue
+		}
+
+		maskHTMLText(src[last:loc[0]])
+		if !closing && !selfClosing && protectedElements[name] {
+			blockTag = name
+			blockDepth = 1
+			blockStart = loc[0]
+			continue
+		}
+		sb.WriteString(escapePlaceholders(tag, func(s string) string { return s }))
+		last = loc[1]
+	}
+	if blockTag != "" {
+		// Unclosed, protect till end
+		sb.WriteString(placeholder(src[blockStart:]))
+	} else {
+		maskHTMLText(src[last:])
+	}
+
+	return sb.String(), replacements
+}
+
+// replaceOutsidePlaceholders: Replace matches in text between placeholders only, so that placeholders are never nested
+func replaceOutsidePlaceholders(text string, re *regexp.Regexp, repl func(string) string) string {
+	var (
+		sb   strings.Builder
+		last int
+	)
+	for _, loc := range placeholderPattern.FindAllStringIndex(text, -1) {
+		sb.WriteString(re.ReplaceAllStringFunc(text[last:loc[0]], repl))
+		sb.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	sb.WriteString(re.ReplaceAllStringFunc(text[last:], repl))
+
+	return sb.String()
+}
+
+// Fingerprint: Identify masking rules applied for target language, empty if nothing is masked
+func (m *Masker) Fingerprint(targetLang string) string {
+	if fingerprint, ok := m.fingerprints[targetLang]; ok {
+		return fingerprint
+	}
+
+	return m.fingerprints[""]
+}
+
+// parseHTMLTag: Get lower cased name of tag, and whether it's a closing or self-closing one
+func parseHTMLTag(tag string) (string, bool, bool) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(tag, "<"), ">")
+	closing := strings.HasPrefix(inner, "/")
+	selfClosing := strings.HasSuffix(inner, "/")
+	inner = strings.TrimPrefix(inner, "/")
+
+	end := strings.IndexAny(inner, " \t\n\r\f/")
+	if end < 0 {
+		end = len(inner)
+	}
+
+	return strings.ToLower(inner[:end]), closing, selfClosing
+}
+
+// Unmask: Restore placeholders with replacements, returns false if any placeholder is lost
+func Unmask(translated string, replacements []string) (string, bool) {
+	if len(replacements) == 0 {
+		return translated, true
+	}
+
+	restored := make([]bool, len(replacements))
+
+	// Placeholders are never nested, and replacements may contain placeholder-like text, so restore only once
+	translated = placeholderPattern.ReplaceAllStringFunc(translated, func(p string) string {
+		index, err := strconv.Atoi(placeholderPattern.FindStringSubmatch(p)[1])
+		if err != nil || index >= len(replacements) {
+			return p
+		}
+		restored[index] = true
+		return replacements[index]
+	})
+
+	for _, ok := range restored {
+		if !ok {
+			return translated, false
+		}
+	}
+
+	return translated, true
+}
+
+// OnlyPlaceholders: Check if there is nothing to translate after mask
+func OnlyPlaceholders(masked string) bool {
+	return strings.TrimSpace(placeholderPattern.ReplaceAllString(masked, "")) == ""
+}
@@ -0,0 +1,157 @@
+package translate
+
+import "testing"
+
+func TestMask(t *testing.T) {
+	protect := []string{`@\w+`, `#\w+`, `https?://[^\s<>"]+`}
+	glossary := map[string]map[string]string{
+		"zh": {"RSSHub": "RSSHub", "Smart Layer": "智能层"},
+	}
+
+	tests := []struct {
+		name         string
+		src          string
+		isHTML       bool
+		wantMasked   string
+		replacements []string
+		wantUnmasked string // Checked if not empty
+	}{
+		{
+			name:         "text",
+			src:          "Thanks @alice for RSSHub #news https://example.com/a",
+			wantMasked:   "Thanks {{0}} for {{3}} {{1}} {{2}}",
+			replacements: []string{"@alice", "#news", "https://example.com/a", "RSSHub"},
+		},
+		{
+			name:         "html keeps entities and attributes",
+			src:          `It&#39;s <a href="#top">#tag</a>`,
+			isHTML:       true,
+			wantMasked:   `It's <a href="#top">{{0}}</a>`,
+			replacements: []string{"#tag"},
+		},
+		{
+			name:         "html escapes restored text",
+			src:          `<p>Smart Layer &amp; @bob &lt;3</p>`,
+			isHTML:       true,
+			wantMasked:   `<p>{{1}} &amp; {{0}} &lt;3</p>`,
+			replacements: []string{"@bob", "智能层"},
+		},
+		{
+			name:         "html code blocks",
+			src:          `<p>Run <code>echo #1</code> then <pre><code>@x</code></pre></p>`,
+			isHTML:       true,
+			wantMasked:   `<p>Run {{0}} then {{1}}</p>`,
+			replacements: []string{"<code>echo #1</code>", "<pre><code>@x</code></pre>"},
+		},
+		{
+			name:         "html unclosed code block",
+			src:          `<p>a</p><pre>#x`,
+			isHTML:       true,
+			wantMasked:   `<p>a</p>{{0}}`,
+			replacements: []string{"<pre>#x"},
+		},
+		{
+			name:         "placeholder-like text",
+			src:          "literal {{0}} and @bob",
+			wantMasked:   "literal {{0}} and {{1}}",
+			replacements: []string{"{{0}}", "@bob"},
+			wantUnmasked: "literal {{0}} and @bob",
+		},
+		{
+			name:         "no nested placeholders",
+			src:          "{{ 1 }}@bob",
+			wantMasked:   "{{0}}{{1}}",
+			replacements: []string{"{{ 1 }}", "@bob"},
+			wantUnmasked: "{{ 1 }}@bob",
+		},
+		{
+			name:         "html placeholder-like text",
+			src:          `<a title="{{1}}">Fix {{0}} for @bob</a><code>{{2}}</code>`,
+			isHTML:       true,
+			wantMasked:   `<a title="{{0}}">Fix {{1}} for {{2}}</a>{{3}}`,
+			replacements: []string{"{{1}}", "{{0}}", "@bob", "<code>{{2}}</code>"},
+			wantUnmasked: `<a title="{{1}}">Fix {{0}} for @bob</a><code>{{2}}</code>`,
+		},
+		{
+			name:       "nothing to mask",
+			src:        "plain words",
+			wantMasked: "plain words",
+		},
+	}
+
+	m, err := NewMasker(protect, glossary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			masked, replacements := m.Mask(tt.src, "zh", tt.isHTML)
+			if masked != tt.wantMasked {
+				t.Errorf("Mask() masked = %q, want %q", masked, tt.wantMasked)
+			}
+			if len(replacements) != len(tt.replacements) {
+				t.Fatalf("Mask() replacements = %q, want %q", replacements, tt.replacements)
+			}
+			for i := range replacements {
+				if replacements[i] != tt.replacements[i] {
+					t.Errorf("Mask() replacements[%d] = %q, want %q", i, replacements[i], tt.replacements[i])
+				}
+			}
+
+			// Round trip, with glossary terms translated
+			unmasked, ok := Unmask(masked, replacements)
+			if !ok {
+				t.Errorf("Unmask() lost placeholders of %q", masked)
+			}
+			if tt.wantUnmasked != "" && unmasked != tt.wantUnmasked {
+				t.Errorf("Unmask() = %q, want %q", unmasked, tt.wantUnmasked)
+			}
+		})
+	}
+}
+
+func TestUnmask(t *testing.T) {
+	tests := []struct {
+		name         string
+		translated   string
+		replacements []string
+		want         string
+		ok           bool
+	}{
+		{"restore", "谢谢 {{0}}，{{1}}", []string{"@alice", "#news"}, "谢谢 @alice，#news", true},
+		{"spaces inside placeholder", "谢谢 {{ 0 }}", []string{"@alice"}, "谢谢 @alice", true},
+		{"reordered", "{{1}} {{0}}", []string{"a", "b"}, "b a", true},
+		{"lost placeholder", "谢谢", []string{"@alice"}, "谢谢", false},
+		{"unknown index kept", "{{5}}", []string{"a"}, "{{5}}", false},
+		{"no replacements", "text {{0}}", nil, "text {{0}}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Unmask(tt.translated, tt.replacements)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Unmask() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestMaskerFingerprint(t *testing.T) {
+	base, _ := NewMasker([]string{`@\w+`}, map[string]map[string]string{"zh": {"a": "b"}})
+	other, _ := NewMasker([]string{`@\w+`}, map[string]map[string]string{"zh": {"a": "c"}})
+	empty, _ := NewMasker(nil, nil)
+
+	if empty.Fingerprint("zh") != "" {
+		t.Errorf("Fingerprint() of empty masker = %q, want empty", empty.Fingerprint("zh"))
+	}
+	if base.Fingerprint("zh") == other.Fingerprint("zh") {
+		t.Error("Fingerprint() should differ when glossary changes")
+	}
+	if base.Fingerprint("ja") != other.Fingerprint("ja") {
+		t.Error("Fingerprint() should not differ for languages without glossary")
+	}
+	if base.Fingerprint("ja") == base.Fingerprint("zh") {
+		t.Error("Fingerprint() should differ between languages with different glossary")
+	}
+}
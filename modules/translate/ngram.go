@@ -0,0 +1,179 @@
+package translate
+
+import (
+	_ "embed"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	trigramProfileSize = 300
+	trigramMaxDistance = trigramProfileSize // Distance of trigram missing from text
+)
+
+//go:embed trigrams.tsv
+var trigramData string
+
+type trigramProfile struct {
+	lang     string
+	trigrams []string // By rank
+}
+
+var (
+	trigramProfilesOnce sync.Once
+	trigramProfiles     map[string][]trigramProfile // By script
+)
+
+// Scripts shared by several languages, which are told apart by trigram profiles
+var trigramScripts = map[string]*unicode.RangeTable{
+	"Latin":      unicode.Latin,
+	"Cyrillic":   unicode.Cyrillic,
+	"Arabic":     unicode.Arabic,
+	"Devanagari": unicode.Devanagari,
+	"Hebrew":     unicode.Hebrew,
+	"Ethiopic":   unicode.Ethiopic,
+}
+
+func loadTrigramProfiles() {
+	trigramProfiles = make(map[string][]trigramProfile)
+	for _, line := range strings.Split(trigramData, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		trigramProfiles[fields[0]] = append(trigramProfiles[fields[0]], trigramProfile{
+			lang:     fields[1],
+			trigrams: strings.Split(fields[2], "|"),
+		})
+	}
+}
+
+// DetectNgram: Detect language offline by comparing trigram ranks of text with language profiles
+// of its dominant script. Returns empty language if script is not covered.
+func DetectNgram(text string) (string, float64) {
+	trigramProfilesOnce.Do(loadTrigramProfiles)
+
+	// Find dominant script
+	counts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		for script, table := range trigramScripts {
+			if unicode.Is(table, r) {
+				counts[script]++
+				break
+			}
+		}
+	}
+	var script string
+	for s, count := range counts {
+		if script == "" || count > counts[script] || (count == counts[script] && s < script) {
+			script = s
+		}
+	}
+	profiles := trigramProfiles[script]
+	if len(profiles) == 0 {
+		return "", 0
+	}
+
+	ranks := trigramRanks(text)
+	if len(ranks) == 0 {
+		return "", 0
+	}
+
+	// Score by total distance, lower is closer
+	type score struct {
+		lang  string
+		score int
+	}
+	maxTotal := trigramProfileSize * trigramMaxDistance
+	scores := make([]score, 0, len(profiles))
+	for _, profile := range profiles {
+		total := 0
+		for i, trigram := range profile.trigrams {
+			if rank, ok := ranks[trigram]; ok {
+				total += abs(rank - i)
+			} else {
+				total += trigramMaxDistance
+			}
+		}
+		scores = append(scores, score{profile.lang, maxTotal - total})
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].score > scores[j].score
+	})
+
+	best := scores[0]
+	if best.score <= 0 {
+		return "", 0
+	}
+	if len(scores) == 1 || scores[1].score <= 0 {
+		return best.lang, min(float64(best.score)/500, 1)
+	}
+
+	// Confidence by relative gap to the second best, short texts need larger gaps
+	rate := float64(best.score-scores[1].score) / float64(scores[1].score)
+	confidentRate := 12/float64(len(ranks)) + 0.05
+
+	return best.lang, min(rate/confidentRate, 1)
+}
+
+// trigramRanks: Rank trigrams of text by frequency, ties ordered by trigram.
+// Letters are lower cased and everything else is treated as word boundary.
+func trigramRanks(text string) map[string]int {
+	// Normalize to single space separated words, padded with spaces
+	var b strings.Builder
+	b.WriteRune(' ')
+	space := true
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r) {
+			b.WriteRune(unicode.ToLower(r))
+			space = false
+		} else if !space {
+			b.WriteRune(' ')
+			space = true
+		}
+	}
+	if !space {
+		b.WriteRune(' ')
+	}
+	runes := []rune(b.String())
+
+	// Count
+	counts := make(map[string]int)
+	for i := 0; i+3 <= len(runes); i++ {
+		counts[string(runes[i:i+3])]++
+	}
+
+	trigrams := make([]string, 0, len(counts))
+	for trigram := range counts {
+		trigrams = append(trigrams, trigram)
+	}
+	sort.Slice(trigrams, func(i, j int) bool {
+		if counts[trigrams[i]] != counts[trigrams[j]] {
+			return counts[trigrams[i]] > counts[trigrams[j]]
+		}
+		return trigrams[i] < trigrams[j]
+	})
+
+	ranks := make(map[string]int, len(trigrams))
+	for i, trigram := range trigrams {
+		ranks[trigram] = i
+	}
+
+	return ranks
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,3 @@
+// Package builtin registers all built-in translate providers.
+// Each provider can be excluded with build tag no_<provider>, e.g. no_libretranslate.
+package builtin
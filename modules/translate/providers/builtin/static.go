@@ -0,0 +1,5 @@
+//go:build !no_static
+
+package builtin
+
+import _ "github.com/candinya/rsshub-smart-layer/modules/translate/providers/static"
@@ -0,0 +1,70 @@
+package libretranslate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+type libreTranslateDetectRequestBody struct {
+	Q      string `json:"q"`
+	APIKey string `json:"api_key"`
+}
+
+type libreTranslateDetectResponseBody []struct {
+	Confidence float64 `json:"confidence"`
+	Language   string  `json:"language"`
+}
+
+func (t *lt) Detect(ctx context.Context, text string) (string, float64, error) {
+	// Prepare request body
+	reqBody := &libreTranslateDetectRequestBody{
+		Q: text,
+	}
+
+	if t.key != nil {
+		reqBody.APIKey = *t.key
+	}
+
+	t.l.Debug("detect request", zap.Any("body", reqBody))
+
+	reqBodyBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	// Create request
+	req, err := http.NewRequestWithContext(ctx, "POST", t.detectUrl, bytes.NewReader(reqBodyBytes))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	// Execute request
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to execute request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	var resBody libreTranslateDetectResponseBody
+	err = json.NewDecoder(res.Body).Decode(&resBody)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	t.l.Debug("detect response", zap.Any("body", resBody))
+
+	if len(resBody) == 0 {
+		return "", 0, fmt.Errorf("no language detected")
+	}
+
+	// Results are sorted by confidence, which is in percent
+	return resBody[0].Language, resBody[0].Confidence / 100, nil
+}
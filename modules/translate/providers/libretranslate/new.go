@@ -2,6 +2,7 @@ package libretranslate
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/candinya/rsshub-smart-layer/modules/translate"
 	"go.uber.org/zap"
@@ -15,9 +16,16 @@ func New(settings string, l *zap.Logger) (translate.Provider, error) {
 		return nil, fmt.Errorf("libretranslate config parse err: %v", err)
 	}
 
+	// Detect endpoint is next to translate endpoint by default
+	detectUrl := cfg.API.DetectURL
+	if detectUrl == "" {
+		detectUrl = strings.TrimSuffix(cfg.API.URL, "/translate") + "/detect"
+	}
+
 	return &lt{
-		l:   l,
-		url: cfg.API.URL,
-		key: cfg.API.Key,
+		l:         l,
+		url:       cfg.API.URL,
+		detectUrl: detectUrl,
+		key:       cfg.API.Key,
 	}, nil
 }
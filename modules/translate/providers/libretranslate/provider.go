@@ -5,18 +5,24 @@ import (
 	"go.uber.org/zap"
 )
 
-var _ translate.Provider = (*lt)(nil)
+var (
+	_ translate.Provider      = (*lt)(nil)
+	_ translate.Detector      = (*lt)(nil)
+	_ translate.BatchProvider = (*lt)(nil)
+)
 
 type lt struct {
 	l *zap.Logger
 
-	url string
-	key *string
+	url       string
+	detectUrl string
+	key       *string
 }
 
 type ltCfg struct {
 	API struct {
-		URL string  `yaml:"url"`
-		Key *string `yaml:"key,omitempty"`
+		URL       string  `yaml:"url"`
+		DetectURL string  `yaml:"detect_url,omitempty"`
+		Key       *string `yaml:"key,omitempty"`
 	} `yaml:"api"`
 }
@@ -0,0 +1,13 @@
+package libretranslate
+
+import "github.com/candinya/rsshub-smart-layer/modules/translate/providers"
+
+const schema = `api:
+  url: "http://localhost:5000/translate" # Translate endpoint
+  detect_url: "http://localhost:5000/detect" # Optional, next to translate endpoint by default
+  key: "" # Optional API key
+`
+
+func init() {
+	providers.Register("libretranslate", New, schema)
+}
@@ -2,6 +2,7 @@ package libretranslate
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,7 +11,7 @@ import (
 )
 
 type libreTranslateRequestBody struct {
-	Q      string  `json:"q"`
+	Q      any     `json:"q"` // string, or []string for batch
 	Source string  `json:"source"`
 	Target string  `json:"target"`
 	Format *string `json:"format,omitempty"`
@@ -21,14 +22,48 @@ type libreTranslateResponseBody struct {
 	TranslatedText string `json:"translatedText"`
 }
 
+type libreTranslateBatchResponseBody struct {
+	TranslatedText []string `json:"translatedText"`
+}
+
 var htmlFormat = "html" // Use as constant
 
-func (t *lt) Translate(src string, lang string, isHTML bool) (*string, error) {
+func (t *lt) Translate(ctx context.Context, src string, sourceLang string, targetLang string, isHTML bool) (*string, error) {
+	var resBody libreTranslateResponseBody
+	err := t.translate(ctx, src, sourceLang, targetLang, isHTML, &resBody)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return translated result
+	return &resBody.TranslatedText, nil
+}
+
+func (t *lt) TranslateBatch(ctx context.Context, srcs []string, sourceLang string, targetLang string, isHTML bool) ([]string, error) {
+	var resBody libreTranslateBatchResponseBody
+	err := t.translate(ctx, srcs, sourceLang, targetLang, isHTML, &resBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resBody.TranslatedText) != len(srcs) {
+		return nil, fmt.Errorf("translated count mismatch: %d != %d", len(resBody.TranslatedText), len(srcs))
+	}
+
+	// Return translated results
+	return resBody.TranslatedText, nil
+}
+
+func (t *lt) translate(ctx context.Context, q any, sourceLang string, targetLang string, isHTML bool, resBody any) error {
 	// Prepare request body
 	reqBody := &libreTranslateRequestBody{
-		Q:      src,
-		Source: "auto", // Auto detect
-		Target: lang,   // Specified by request
+		Q:      q,
+		Source: "auto",     // Auto detect
+		Target: targetLang, // Specified by request
+	}
+
+	if sourceLang != "" {
+		reqBody.Source = sourceLang
 	}
 
 	if isHTML {
@@ -43,13 +78,13 @@ func (t *lt) Translate(src string, lang string, isHTML bool) (*string, error) {
 
 	reqBodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		return fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
 	// Create request
-	req, err := http.NewRequest("POST", t.url, bytes.NewReader(reqBodyBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", t.url, bytes.NewReader(reqBodyBytes))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -57,19 +92,17 @@ func (t *lt) Translate(src string, lang string, isHTML bool) (*string, error) {
 	// Execute request
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return fmt.Errorf("failed to execute request: %w", err)
 	}
 
 	defer res.Body.Close()
 
-	var resBody libreTranslateResponseBody
-	err = json.NewDecoder(res.Body).Decode(&resBody)
+	err = json.NewDecoder(res.Body).Decode(resBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	t.l.Debug("translate response", zap.Any("body", resBody))
 
-	// Return translated result
-	return &resBody.TranslatedText, nil
+	return nil
 }
@@ -2,18 +2,79 @@ package providers
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/candinya/rsshub-smart-layer/modules/translate"
-	"github.com/candinya/rsshub-smart-layer/modules/translate/providers/libretranslate"
-	"github.com/candinya/rsshub-smart-layer/types"
 	"go.uber.org/zap"
 )
 
-func NewTranslator(cfg *types.ConfigTranslate, l *zap.Logger) (translate.Provider, error) {
-	switch cfg.Provider {
-	case "libretranslate":
-		return libretranslate.New(cfg.Settings, l)
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", cfg.Provider)
+// Factory creates a provider with its settings
+type Factory func(settings string, l *zap.Logger) (translate.Provider, error)
+
+// Info describes a registered provider
+type Info struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"` // Example settings
+}
+
+type registration struct {
+	factory Factory
+	schema  string
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]registration)
+)
+
+// Register: Make a provider available by name, usually called in init of provider package.
+// Panics if name is already registered or factory is nil.
+func Register(name string, factory Factory, schema string) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if factory == nil {
+		panic("providers: Register factory is nil for " + name)
+	}
+	if _, ok := registry[name]; ok {
+		panic("providers: Register called twice for " + name)
+	}
+
+	registry[name] = registration{
+		factory: factory,
+		schema:  schema,
 	}
 }
+
+// List: Get all registered providers sorted by name
+func List() []Info {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	list := make([]Info, 0, len(registry))
+	for name, r := range registry {
+		list = append(list, Info{
+			Name:   name,
+			Schema: r.schema,
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+func NewTranslator(provider string, settings string, l *zap.Logger) (translate.Provider, error) {
+	registryLock.RLock()
+	r, ok := registry[provider]
+	registryLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+
+	return r.factory(settings, l)
+}
@@ -0,0 +1,113 @@
+package static
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/candinya/rsshub-smart-layer/modules/translate"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+func New(settings string, l *zap.Logger) (translate.Provider, error) {
+	var cfg stCfg
+	err := yaml.Unmarshal([]byte(settings), &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("static config parse err: %v", err)
+	}
+
+	t := &st{
+		l:          l,
+		ignoreCase: cfg.IgnoreCase,
+	}
+
+	// Load tables in directory
+	if cfg.Dir != "" {
+		files, err := filepath.Glob(filepath.Join(cfg.Dir, "*.yml"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tables in %s: %w", cfg.Dir, err)
+		}
+
+		for _, file := range files {
+			source, target, ok := strings.Cut(strings.TrimSuffix(filepath.Base(file), ".yml"), "-")
+			if !ok {
+				l.Warn("skip phrase table with invalid name", zap.String("file", file))
+				continue
+			}
+			if source == "any" {
+				source = ""
+			}
+
+			err = t.load(source, target, file)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Load specified tables
+	for _, table := range cfg.Tables {
+		err = t.load(table.Source, table.Target, table.File)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+func (t *st) load(source string, target string, file string) error {
+	tableBytes, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read phrase table %s: %w", file, err)
+	}
+
+	var tableFile stTableFile
+	err = yaml.Unmarshal(tableBytes, &tableFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse phrase table %s: %w", file, err)
+	}
+
+	table := &phraseTable{
+		source: source,
+		target: target,
+		exact:  make(map[string]string),
+	}
+
+	for phrase, translated := range tableFile.Exact {
+		table.exact[t.normalize(phrase)] = translated
+	}
+
+	for _, rule := range tableFile.Regex {
+		pattern := rule.Pattern
+		if t.ignoreCase {
+			pattern = "(?i)" + pattern
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("failed to compile pattern %s in %s: %w", rule.Pattern, file, err)
+		}
+		table.regex = append(table.regex, phraseRule{
+			pattern: re,
+			replace: rule.Replace,
+		})
+	}
+
+	t.l.Debug("phrase table loaded", zap.String("file", file), zap.String("source", source), zap.String("target", target))
+	t.tables = append(t.tables, table)
+
+	return nil
+}
+
+func (t *st) normalize(phrase string) string {
+	phrase = strings.TrimSpace(phrase)
+	if t.ignoreCase {
+		phrase = strings.ToLower(phrase)
+	}
+
+	return phrase
+}
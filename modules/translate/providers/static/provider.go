@@ -0,0 +1,47 @@
+package static
+
+import (
+	"regexp"
+
+	"github.com/candinya/rsshub-smart-layer/modules/translate"
+	"go.uber.org/zap"
+)
+
+var _ translate.Provider = (*st)(nil)
+
+type st struct {
+	l *zap.Logger
+
+	ignoreCase bool
+	tables     []*phraseTable
+}
+
+type phraseTable struct {
+	source string // Empty for any source language
+	target string
+	exact  map[string]string
+	regex  []phraseRule
+}
+
+type phraseRule struct {
+	pattern *regexp.Regexp
+	replace string
+}
+
+type stCfg struct {
+	Dir        string `yaml:"dir"` // Load all <source>-<target>.yml files, use "any" as source for any source language
+	IgnoreCase bool   `yaml:"ignore_case"`
+	Tables     []struct {
+		Source string `yaml:"source"` // Empty for any source language
+		Target string `yaml:"target"`
+		File   string `yaml:"file"`
+	} `yaml:"tables"`
+}
+
+type stTableFile struct {
+	Exact map[string]string `yaml:"exact"`
+	Regex []struct {
+		Pattern string `yaml:"pattern"`
+		Replace string `yaml:"replace"`
+	} `yaml:"regex"`
+}
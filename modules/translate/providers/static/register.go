@@ -0,0 +1,21 @@
+package static
+
+import "github.com/candinya/rsshub-smart-layer/modules/translate/providers"
+
+const schema = `dir: "./phrases" # Optional, load all <source>-<target>.yml files, use "any" as source for any source language
+ignore_case: false
+tables: # Optional, tables with explicit language pair
+  - source: en # Empty for any source language
+    target: zh
+    file: "./phrases/en-zh.yml"
+# Phrase table file format:
+# exact:
+#   Retweeted: 转推了
+# regex:
+#   - pattern: '^(\d+) replies$'
+#     replace: '$1 条回复'
+`
+
+func init() {
+	providers.Register("static", New, schema)
+}
@@ -0,0 +1,59 @@
+package static
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/candinya/rsshub-smart-layer/modules/translate"
+	"go.uber.org/zap"
+)
+
+func (t *st) Translate(_ context.Context, src string, sourceLang string, targetLang string, _ bool) (*string, error) {
+	phrase := t.normalize(src)
+
+	for _, table := range t.matchTables(sourceLang, targetLang) {
+		// Exact match
+		if translated, ok := table.exact[phrase]; ok {
+			t.l.Debug("exact phrase matched", zap.String("src", src), zap.String("translated", translated))
+			return &translated, nil
+		}
+
+		// Regex match
+		trimmed := strings.TrimSpace(src)
+		for _, rule := range table.regex {
+			if rule.pattern.MatchString(trimmed) {
+				translated := rule.pattern.ReplaceAllString(trimmed, rule.replace)
+				t.l.Debug("regex phrase matched", zap.String("src", src), zap.String("pattern", rule.pattern.String()), zap.String("translated", translated))
+				return &translated, nil
+			}
+		}
+	}
+
+	// Report miss, so that the chain can continue
+	t.l.Debug("phrase missed", zap.String("src", src), zap.String("source", sourceLang), zap.String("target", targetLang))
+	if sourceLang == "" {
+		sourceLang = "auto"
+	}
+	return nil, fmt.Errorf("static %s -> %s: %w", sourceLang, targetLang, translate.ErrNoTranslation)
+}
+
+// matchTables: Tables of exact source language first, then tables for any source language.
+// All tables of target language are matched if source language is unknown.
+func (t *st) matchTables(sourceLang string, targetLang string) []*phraseTable {
+	var exact, anySource []*phraseTable
+	for _, table := range t.tables {
+		if !strings.EqualFold(table.target, targetLang) {
+			continue
+		}
+
+		switch {
+		case table.source == "":
+			anySource = append(anySource, table)
+		case sourceLang == "" || strings.EqualFold(table.source, sourceLang):
+			exact = append(exact, table)
+		}
+	}
+
+	return append(exact, anySource...)
+}
@@ -0,0 +1,128 @@
+package static
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/candinya/rsshub-smart-layer/modules/translate"
+	"go.uber.org/zap"
+)
+
+func newTestProvider(t *testing.T, ignoreCase bool) translate.Provider {
+	t.Helper()
+
+	dir := t.TempDir()
+	tables := map[string]string{
+		"en-zh.yml": `
+exact:
+  Retweeted: 转推了
+regex:
+  - pattern: '^(\d+) replies$'
+    replace: '$1 条回复'
+`,
+		"ja-zh.yml": `
+exact:
+  Retweeted: 转推（日）
+`,
+		"any-zh.yml": `
+exact:
+  Retweeted: 转推（任意）
+  OK: 好
+`,
+		"invalid.yml": `
+exact:
+  OK: skipped
+`,
+	}
+	for name, content := range tables {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	explicit := filepath.Join(t.TempDir(), "explicit.yml")
+	if err := os.WriteFile(explicit, []byte("exact:\n  Retweeted: リツイート\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := fmt.Sprintf("dir: %q\nignore_case: %v\ntables:\n  - source: en\n    target: ja\n    file: %q\n", dir, ignoreCase, explicit)
+	p, err := New(settings, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestStaticTranslate(t *testing.T) {
+	tests := []struct {
+		name       string
+		ignoreCase bool
+		src        string
+		sourceLang string
+		targetLang string
+		want       string // Empty for miss
+	}{
+		{"exact", false, "Retweeted", "en", "zh", "转推了"},
+		{"exact trimmed", false, "  Retweeted\n", "en", "zh", "转推了"},
+		{"regex", false, "12 replies", "en", "zh", "12 条回复"},
+		{"source table first", false, "Retweeted", "ja", "zh", "转推（日）"},
+		{"any source table", false, "Retweeted", "fr", "zh", "转推（任意）"},
+		{"any source table as fallback", false, "OK", "en", "zh", "好"},
+		{"unknown source in load order", false, "Retweeted", "", "zh", "转推了"},
+		{"explicit table", false, "Retweeted", "en", "ja", "リツイート"},
+		{"case sensitive", false, "retweeted", "en", "zh", ""},
+		{"case sensitive regex", false, "12 REPLIES", "en", "zh", ""},
+		{"ignore case", true, "retweeted", "en", "zh", "转推了"},
+		{"ignore case regex", true, "12 REPLIES", "en", "zh", "12 条回复"},
+		{"miss", false, "Liked", "en", "zh", ""},
+		{"no table for target", false, "Retweeted", "en", "de", ""},
+		{"invalid table name skipped", false, "OK", "fr", "ja", ""},
+	}
+
+	providers := map[bool]translate.Provider{
+		false: newTestProvider(t, false),
+		true:  newTestProvider(t, true),
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			translated, err := providers[tt.ignoreCase].Translate(context.Background(), tt.src, tt.sourceLang, tt.targetLang, false)
+			if tt.want == "" {
+				// Miss must be reported, so that next provider in chain is tried
+				if !errors.Is(err, translate.ErrNoTranslation) {
+					t.Errorf("Translate() error = %v, want ErrNoTranslation", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Translate() error = %v", err)
+			}
+			if *translated != tt.want {
+				t.Errorf("Translate() = %q, want %q", *translated, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticNewInvalid(t *testing.T) {
+	dir := t.TempDir()
+	badPattern := filepath.Join(dir, "en-zh.yml")
+	if err := os.WriteFile(badPattern, []byte("regex:\n  - pattern: '('\n    replace: x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, settings := range map[string]string{
+		"invalid settings": "tables: [",
+		"missing table":    fmt.Sprintf("tables:\n  - target: zh\n    file: %q\n", filepath.Join(dir, "missing.yml")),
+		"invalid pattern":  fmt.Sprintf("dir: %q\n", dir),
+	} {
+		if _, err := New(settings, zap.NewNop()); err == nil {
+			t.Errorf("New() with %s should fail", name)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package translate
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Content of these elements is never translated
+var skipElements = map[atom.Atom]bool{
+	atom.Code:     true,
+	atom.Pre:      true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Textarea: true,
+	atom.Kbd:      true,
+	atom.Samp:     true,
+}
+
+// Segments is a parsed HTML fragment with its translatable text nodes
+type Segments struct {
+	nodes []*html.Node
+	texts []*html.Node
+}
+
+// SegmentHTML: Parse HTML fragment and extract text nodes, skipping code blocks, scripts and whitespaces.
+// Attribute values are never extracted.
+func SegmentHTML(src string) (*Segments, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(src), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	s := &Segments{
+		nodes: nodes,
+	}
+	for _, node := range nodes {
+		s.collect(node)
+	}
+
+	return s, nil
+}
+
+func (s *Segments) collect(n *html.Node) {
+	if n.Type == html.ElementNode && skipElements[n.DataAtom] {
+		return
+	}
+
+	if n.Type == html.TextNode && strings.TrimSpace(n.Data) != "" {
+		s.texts = append(s.texts, n)
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		s.collect(c)
+	}
+}
+
+// Texts: Get text of all segments, without surrounding whitespaces
+func (s *Segments) Texts() []string {
+	texts := make([]string, len(s.texts))
+	for i, n := range s.texts {
+		texts[i] = strings.TrimSpace(n.Data)
+	}
+
+	return texts
+}
+
+// Render: Replace segments with translated texts (in same order as Texts) and render back to HTML
+func (s *Segments) Render(translated []string) (string, error) {
+	if len(translated) != len(s.texts) {
+		return "", fmt.Errorf("segments count mismatch: %d != %d", len(translated), len(s.texts))
+	}
+
+	for i, n := range s.texts {
+		// Keep surrounding whitespaces
+		leading := n.Data[:len(n.Data)-len(strings.TrimLeftFunc(n.Data, unicode.IsSpace))]
+		trailing := n.Data[len(strings.TrimRightFunc(n.Data, unicode.IsSpace)):]
+		n.Data = leading + translated[i] + trailing
+	}
+
+	var b bytes.Buffer
+	for _, node := range s.nodes {
+		err := html.Render(&b, node)
+		if err != nil {
+			return "", fmt.Errorf("failed to render html: %w", err)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// SplitText: Split text into pieces no longer than maxChars (in runes), preferably at sentence ends
+func SplitText(text string, maxChars int) []string {
+	if maxChars <= 0 || utf8.RuneCountInString(text) <= maxChars {
+		return []string{text}
+	}
+
+	var (
+		pieces  []string
+		current strings.Builder
+		count   int
+	)
+	flush := func() {
+		if current.Len() > 0 {
+			pieces = append(pieces, current.String())
+			current.Reset()
+			count = 0
+		}
+	}
+
+	for _, sentence := range splitSentences(text) {
+		sentenceCount := utf8.RuneCountInString(sentence)
+
+		// Sentence itself too long, hard split
+		if sentenceCount > maxChars {
+			flush()
+			runes := []rune(sentence)
+			for len(runes) > maxChars {
+				pieces = append(pieces, string(runes[:maxChars]))
+				runes = runes[maxChars:]
+			}
+			current.WriteString(string(runes))
+			count = len(runes)
+			continue
+		}
+
+		if count+sentenceCount > maxChars {
+			flush()
+		}
+		current.WriteString(sentence)
+		count += sentenceCount
+	}
+	flush()
+
+	return pieces
+}
+
+// splitSentences: Split after sentence end punctuations, keeping all characters
+func splitSentences(text string) []string {
+	var (
+		sentences []string
+		start     int
+	)
+
+	for i, r := range text {
+		switch r {
+		case '.', '!', '?', ';', '\n', '。', '！', '？', '；':
+			end := i + utf8.RuneLen(r)
+			sentences = append(sentences, text[start:end])
+			start = end
+		}
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+
+	return sentences
+}
@@ -0,0 +1,112 @@
+package translate
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSegmentHTML(t *testing.T) {
+	tests := []struct {
+		name       string
+		src        string
+		wantTexts  []string
+		translated []string
+		want       string
+	}{
+		{
+			name:       "paragraphs",
+			src:        `<p>Hello <b>world</b></p><p> Bye </p>`,
+			wantTexts:  []string{"Hello", "world", "Bye"},
+			translated: []string{"你好", "世界", "再见"},
+			want:       `<p>你好 <b>世界</b></p><p> 再见 </p>`,
+		},
+		{
+			name:       "skip code and attributes",
+			src:        `<a href="x" title="Title">Link</a><pre>keep me</pre><code>x := 1</code><script>var a</script>`,
+			wantTexts:  []string{"Link"},
+			translated: []string{"链接"},
+			want:       `<a href="x" title="Title">链接</a><pre>keep me</pre><code>x := 1</code><script>var a</script>`,
+		},
+		{
+			name:       "void elements",
+			src:        `Hello world<br>second line<br><img src="x">`,
+			wantTexts:  []string{"Hello world", "second line"},
+			translated: []string{"你好世界", "第二行"},
+			want:       `你好世界<br/>第二行<br/><img src="x"/>`,
+		},
+		{
+			name:       "escaped text",
+			src:        `<p>a &lt; b &amp; c</p>`,
+			wantTexts:  []string{"a < b & c"},
+			translated: []string{"甲 < 乙 & 丙"},
+			want:       `<p>甲 &lt; 乙 &amp; 丙</p>`,
+		},
+		{
+			name:       "whitespace only",
+			src:        "<p> </p>\n",
+			wantTexts:  []string{},
+			translated: []string{},
+			want:       "<p> </p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, err := SegmentHTML(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if texts := segments.Texts(); !slices.Equal(texts, tt.wantTexts) {
+				t.Errorf("Texts() = %q, want %q", texts, tt.wantTexts)
+			}
+
+			got, err := segments.Render(tt.translated)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSegmentsRenderMismatch(t *testing.T) {
+	segments, err := SegmentHTML(`<p>a</p><p>b</p>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = segments.Render([]string{"a"}); err == nil {
+		t.Error("Render() with fewer texts should fail")
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxChars int
+		want     []string
+	}{
+		{"short", "Hello.", 10, []string{"Hello."}},
+		{"unlimited", "Hello. World.", 0, []string{"Hello. World."}},
+		{"at sentence ends", "One. Two. Three.", 10, []string{"One. Two.", " Three."}},
+		{"cjk punctuation", "第一句。第二句。", 4, []string{"第一句。", "第二句。"}},
+		{"hard split", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitText(tt.text, tt.maxChars)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SplitText() = %q, want %q", got, tt.want)
+			}
+			if strings.Join(got, "") != tt.text {
+				t.Errorf("SplitText() lost characters: %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package translate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Validator checks translated results for common provider failures
+type Validator struct {
+	minRatio        float64
+	maxRatio        float64
+	rejectUnchanged bool
+	errorPatterns   []*regexp.Regexp
+}
+
+func NewValidator(cfg *types.ConfigTranslateValidate) (*Validator, error) {
+	v := &Validator{
+		minRatio:        cfg.MinRatio,
+		maxRatio:        cfg.MaxRatio,
+		rejectUnchanged: cfg.RejectUnchanged,
+	}
+
+	for _, p := range cfg.ErrorPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile error pattern %s: %w", p, err)
+		}
+		v.errorPatterns = append(v.errorPatterns, re)
+	}
+
+	return v, nil
+}
+
+// Validate: Returns reason if translated result is suspicious
+func (v *Validator) Validate(src string, translated string, isHTML bool) error {
+	// Non-empty
+	if strings.TrimSpace(translated) == "" {
+		return fmt.Errorf("empty result")
+	}
+
+	// Not untouched source
+	if v.rejectUnchanged && strings.TrimSpace(translated) == strings.TrimSpace(src) {
+		return fmt.Errorf("result is same as source")
+	}
+
+	// Not an error message
+	for _, re := range v.errorPatterns {
+		if re.MatchString(translated) {
+			return fmt.Errorf("result matches error pattern %s", re.String())
+		}
+	}
+
+	// Length ratio within bounds
+	srcLen := utf8.RuneCountInString(src)
+	if srcLen > 0 && (v.minRatio > 0 || v.maxRatio > 0) {
+		ratio := float64(utf8.RuneCountInString(translated)) / float64(srcLen)
+		if v.minRatio > 0 && ratio < v.minRatio {
+			return fmt.Errorf("length ratio %.2f below %.2f", ratio, v.minRatio)
+		}
+		if v.maxRatio > 0 && ratio > v.maxRatio {
+			return fmt.Errorf("length ratio %.2f above %.2f", ratio, v.maxRatio)
+		}
+	}
+
+	// Tags are kept, which also detects truncated HTML
+	if isHTML {
+		if err := compareTags(src, translated); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Void elements have no content nor end tag, so they are rendered either as <br> or <br/>
+var voidElements = map[string]bool{
+	"area":   true,
+	"base":   true,
+	"br":     true,
+	"col":    true,
+	"embed":  true,
+	"hr":     true,
+	"img":    true,
+	"input":  true,
+	"link":   true,
+	"meta":   true,
+	"source": true,
+	"track":  true,
+	"wbr":    true,
+}
+
+// compareTags: Check elements of both sides, as parsed trees so that implicitly closed tags
+// (e.g. <p>a<p>b rendered back as <p>a</p><p>b</p>) are the same.
+// Translated result may close tags left open in source, but not leave more of them open, which means truncated.
+func compareTags(src string, translated string) error {
+	srcElements, err := countElements(src)
+	if err != nil {
+		return err
+	}
+	translatedElements, err := countElements(translated)
+	if err != nil {
+		return err
+	}
+	for tag, count := range srcElements {
+		if translatedElements[tag] != count {
+			return fmt.Errorf("unbalanced tag %s: %d != %d", tag, translatedElements[tag], count)
+		}
+	}
+	for tag, count := range translatedElements {
+		if _, ok := srcElements[tag]; !ok {
+			return fmt.Errorf("unbalanced tag %s: %d != 0", tag, count)
+		}
+	}
+
+	srcTags := countTags(src)
+	for tag, count := range countTags(translated) {
+		if count > srcTags[tag] {
+			return fmt.Errorf("unclosed tag %s: %d > %d", tag, count, srcTags[tag])
+		}
+	}
+
+	return nil
+}
+
+// countElements: Count elements by tag name in parsed HTML fragment, void elements are ignored
+func countElements(htmlText string) (map[string]int, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(htmlText), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	counts := make(map[string]int)
+	var count func(n *html.Node)
+	count = func(n *html.Node) {
+		if n.Type == html.ElementNode && !voidElements[n.Data] {
+			counts[n.Data]++
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			count(c)
+		}
+	}
+	for _, node := range nodes {
+		count(node)
+	}
+
+	return counts, nil
+}
+
+// countTags: Count start tags minus end tags by tag name, void elements are ignored.
+// Self-closing tags of other elements count as start tags, as renderer may write either form.
+func countTags(htmlText string) map[string]int {
+	counts := make(map[string]int)
+
+	z := html.NewTokenizer(strings.NewReader(htmlText))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return counts
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			if !voidElements[string(name)] {
+				counts[string(name)]++
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if !voidElements[string(name)] {
+				counts[string(name)]--
+			}
+		}
+	}
+}
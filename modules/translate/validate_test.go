@@ -0,0 +1,97 @@
+package translate
+
+import (
+	"testing"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+)
+
+func TestValidate(t *testing.T) {
+	v, err := NewValidator(&types.ConfigTranslateValidate{
+		MinRatio:        0.2,
+		MaxRatio:        5,
+		RejectUnchanged: true,
+		ErrorPatterns:   []string{`(?i)^error:`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		src        string
+		translated string
+		isHTML     bool
+		wantErr    bool
+	}{
+		{"ok", "Hello world", "你好，世界！", false, false},
+		{"empty", "Hello world", "  ", false, true},
+		{"unchanged", "Hello world", " Hello world ", false, true},
+		{"error pattern", "Hello world", "Error: quota exceeded", false, true},
+		{"too short", "Hello world, this is a long sentence", "你", false, true},
+		{"too long", "Hi", "This is way too long for a greeting", false, true},
+		{"html ok", "<p>Hello <b>world</b></p>", "<p>你好 <b>世界</b></p>", true, false},
+		{"html truncated", "<p>Hello <b>world</b></p>", "<p>你好 <b>世界", true, true},
+		{"html unclosed extra tag", "<p>Hello world</p>", "<p>你好</p><b>世界", true, true},
+		{
+			// Rendered by segment renderer with self-closing void elements
+			name:       "html void elements",
+			src:        `Hello world<br>second line<br><img src=x>`,
+			translated: `你好世界<br/>第二行<br/><img src="x"/>`,
+			isHTML:     true,
+		},
+		{"html void elements dropped", `a<br>b<br>c`, `甲乙丙`, true, false},
+		{"html self-closing counts as start", `<p>a</p>`, `<p/>甲</p>`, true, false},
+		{"html implicitly closed", `<p>a<p>b`, `<p>甲</p><p>乙</p>`, true, false},
+		{"html implicitly closed kept", `<ul><li>one<li>two</ul>`, `<ul><li>一<li>二</ul>`, true, false},
+		{"html element dropped", `<p>a<p>b`, `<p>甲乙</p>`, true, true},
+		{"html truncated list", `<ul><li>one</li><li>two</li></ul>`, `<ul><li>一</li><li>二`, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(tt.src, tt.translated, tt.isHTML)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSegmentedVoidElements(t *testing.T) {
+	validateSegmented(t, `Hello world<br>second line<br><img src=x>`)
+}
+
+func TestValidateSegmentedImplicitlyClosed(t *testing.T) {
+	for _, src := range []string{
+		`<p>a<p>b`,
+		`<ul><li>one<li>two</ul>`,
+		`<table><tr><td>a<td>b</table>`,
+		`<dl><dt>term<dd>definition</dl>`,
+	} {
+		validateSegmented(t, src)
+	}
+}
+
+// validateSegmented: Translate to itself through segment renderer, which should always pass
+func validateSegmented(t *testing.T, src string) {
+	t.Helper()
+
+	v, err := NewValidator(&types.ConfigTranslateValidate{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	segments, err := SegmentHTML(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	translated, err := segments.Render(segments.Texts())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = v.Validate(src, translated, true); err != nil {
+		t.Errorf("Validate(%q, %q) = %v, want nil", src, translated, err)
+	}
+}
@@ -0,0 +1,186 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/candinya/rsshub-smart-layer/types"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type BudgetState int
+
+const (
+	BudgetOK   BudgetState = iota
+	BudgetSoft             // Soft budget exceeded, translation should be degraded
+	BudgetHard             // Hard budget exceeded, translation should be stopped
+)
+
+func (s BudgetState) String() string {
+	switch s {
+	case BudgetSoft:
+		return "soft"
+	case BudgetHard:
+		return "hard"
+	default:
+		return "ok"
+	}
+}
+
+const (
+	UsagePeriodDay   = "day"
+	UsagePeriodMonth = "month"
+)
+
+const (
+	usageDayExpire   = 40 * 24 * time.Hour
+	usageMonthExpire = 400 * 24 * time.Hour
+)
+
+// UsageMeter counts characters sent to translate providers, all periods are in UTC
+type UsageMeter struct {
+	l     *zap.Logger
+	redis *redis.Client
+
+	prefix  string
+	budgets map[string]types.ConfigTranslateBudget // By provider
+}
+
+type Usage struct {
+	Provider  string           `json:"provider"`
+	Period    string           `json:"period"`
+	Date      string           `json:"date"`
+	Total     int64            `json:"total"`
+	Platforms map[string]int64 `json:"platforms"`
+	Languages map[string]int64 `json:"languages"`
+	Soft      int64            `json:"soft_budget"`
+	Hard      int64            `json:"hard_budget"`
+	State     string           `json:"state,omitempty"` // Current budget state
+}
+
+func NewUsageMeter(redisClient *redis.Client, prefix string, budgets map[string]types.ConfigTranslateBudget, l *zap.Logger) *UsageMeter {
+	return &UsageMeter{
+		l:       l,
+		redis:   redisClient,
+		prefix:  prefix,
+		budgets: budgets,
+	}
+}
+
+func (m *UsageMeter) key(provider string, period string, date time.Time) string {
+	var dateStr string
+	switch period {
+	case UsagePeriodMonth:
+		dateStr = date.UTC().Format("200601")
+	default:
+		dateStr = date.UTC().Format("20060102")
+	}
+
+	return fmt.Sprintf("%s%s:%s:%s:%s", m.prefix, "usage", provider, period, dateStr)
+}
+
+// Record: Add characters to counters of current day and month
+func (m *UsageMeter) Record(ctx context.Context, provider string, platform string, lang string, chars int64) {
+	if chars <= 0 {
+		return
+	}
+
+	now := time.Now()
+	pipe := m.redis.Pipeline()
+	for period, expire := range map[string]time.Duration{
+		UsagePeriodDay:   usageDayExpire,
+		UsagePeriodMonth: usageMonthExpire,
+	} {
+		key := m.key(provider, period, now)
+		pipe.HIncrBy(ctx, key, "total", chars)
+		pipe.HIncrBy(ctx, key, "platform:"+platform, chars)
+		pipe.HIncrBy(ctx, key, "lang:"+lang, chars)
+		pipe.Expire(ctx, key, expire)
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		m.l.Error("failed to record usage", zap.String("provider", provider), zap.Int64("chars", chars), zap.Error(err))
+	}
+}
+
+// Check: Check current usage against budgets
+func (m *UsageMeter) Check(ctx context.Context, provider string) BudgetState {
+	state := BudgetOK
+
+	budget := m.budgets[provider]
+	now := time.Now()
+	for period, limit := range map[string]types.ConfigTranslateBudgetLimit{
+		UsagePeriodDay:   budget.Daily,
+		UsagePeriodMonth: budget.Monthly,
+	} {
+		if limit.Soft <= 0 && limit.Hard <= 0 {
+			continue
+		}
+
+		total, err := m.redis.HGet(ctx, m.key(provider, period, now), "total").Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			m.l.Error("failed to check usage", zap.String("provider", provider), zap.String("period", period), zap.Error(err))
+			continue
+		}
+
+		if limit.Hard > 0 && total >= limit.Hard {
+			m.l.Debug("hard budget exceeded", zap.String("provider", provider), zap.String("period", period), zap.Int64("total", total))
+			return BudgetHard
+		}
+		if limit.Soft > 0 && total >= limit.Soft {
+			m.l.Debug("soft budget exceeded", zap.String("provider", provider), zap.String("period", period), zap.Int64("total", total))
+			state = BudgetSoft
+		}
+	}
+
+	return state
+}
+
+// Usage: Get usage of provider in given period
+func (m *UsageMeter) Usage(ctx context.Context, provider string, period string, date time.Time) (*Usage, error) {
+	key := m.key(provider, period, date)
+	fields, err := m.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	usage := &Usage{
+		Provider:  provider,
+		Period:    period,
+		Date:      key[strings.LastIndex(key, ":")+1:],
+		Platforms: make(map[string]int64),
+		Languages: make(map[string]int64),
+	}
+
+	budget := m.budgets[provider]
+	switch period {
+	case UsagePeriodMonth:
+		usage.Soft, usage.Hard = budget.Monthly.Soft, budget.Monthly.Hard
+	default:
+		usage.Soft, usage.Hard = budget.Daily.Soft, budget.Daily.Hard
+	}
+
+	for field, value := range fields {
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case field == "total":
+			usage.Total = count
+		case strings.HasPrefix(field, "platform:"):
+			usage.Platforms[strings.TrimPrefix(field, "platform:")] = count
+		case strings.HasPrefix(field, "lang:"):
+			usage.Languages[strings.TrimPrefix(field, "lang:")] = count
+		}
+	}
+
+	return usage, nil
+}
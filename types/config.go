@@ -16,8 +16,14 @@ type ConfigSystem struct {
 		Prefix      string        `yaml:"prefix"`
 		CacheExpire time.Duration `yaml:"cache_expire"`
 	} `yaml:"redis"`
-	Listen         string        `yaml:"listen"`
-	RequestTimeout time.Duration `yaml:"request_timeout"`
+	Listen         string             `yaml:"listen"`
+	RequestTimeout time.Duration      `yaml:"request_timeout"`
+	Admin          *ConfigSystemAdmin `yaml:"admin,omitempty"`
+}
+
+type ConfigSystemAdmin struct {
+	Path  string `yaml:"path"`
+	Token string `yaml:"token"`
 }
 
 type ConfigRSSHubList []ConfigRSSHub
@@ -29,18 +35,159 @@ type ConfigRSSHub struct {
 }
 
 type ConfigTranslate struct {
-	Provider    string `yaml:"provider"`
-	DefaultLang string `yaml:"default_lang"`
-	Settings    string `yaml:"settings"`
-	HostBase    string `yaml:"host_base"`
+	Provider         string                             `yaml:"provider"`
+	DefaultLang      string                             `yaml:"default_lang"`
+	Settings         string                             `yaml:"settings"`
+	HostBase         string                             `yaml:"host_base"`
+	QueryParam       string                             `yaml:"query_param"`
+	PathPrefix       string                             `yaml:"path_prefix"`
+	AcceptLanguage   bool                               `yaml:"accept_language"`
+	Languages        []string                           `yaml:"languages"` // Allowlist, empty to accept any well-formed code
+	DetectSource     bool                               `yaml:"detect_source"`
+	DetectConfidence float64                            `yaml:"detect_confidence"` // 0 ~ 1
+	Limit            ConfigTranslateLimit               `yaml:"limit"`
+	Deadline         time.Duration                      `yaml:"deadline"`
+	Async            ConfigTranslateAsync               `yaml:"async"`
+	Budget           ConfigTranslateBudget              `yaml:"budget"`
+	Validate         ConfigTranslateValidate            `yaml:"validate"`
+	Fallbacks        []string                           `yaml:"fallbacks"` // Provider names tried in order when translation fails or is suspicious
+	Prewarm          *ConfigTranslatePrewarm            `yaml:"prewarm,omitempty"`
+	Segment          ConfigTranslateSegment             `yaml:"segment"`
+	Fields           []string                           `yaml:"fields"`    // Parts to translate, empty for item title, description and content
+	Protect          []string                           `yaml:"protect"`   // Regex patterns kept untranslated
+	Glossary         map[string]map[string]string       `yaml:"glossary"`  // Target language -> term -> fixed translation
+	Rules            map[string]ConfigTranslateRule     `yaml:"rules"`     // By platform or feed path prefix (starts with /)
+	Providers        map[string]ConfigTranslateProvider `yaml:"providers"` // Additional named providers
+}
+
+type ConfigTranslateRule struct {
+	Protect    []string                     `yaml:"protect"`
+	Glossary   map[string]map[string]string `yaml:"glossary"`
+	Fields     []string                     `yaml:"fields"`      // Override global fields
+	MaxItems   int                          `yaml:"max_items"`   // Max items to translate per feed, 0 for unlimited
+	SourceLang string                       `yaml:"source_lang"` // Forced source language
+	Provider   string                       `yaml:"provider"`    // Name of provider in providers
+}
+
+type ConfigTranslateProvider struct {
+	Provider string                `yaml:"provider"`
+	Settings string                `yaml:"settings"`
+	Limit    ConfigTranslateLimit  `yaml:"limit"`
+	Budget   ConfigTranslateBudget `yaml:"budget"`
+}
+
+type ConfigTranslateAsync struct {
+	Enable    bool          `yaml:"enable"`
+	Workers   int           `yaml:"workers"`
+	JobExpire time.Duration `yaml:"job_expire"` // How long job status is kept, also prevents duplicated jobs
+}
+
+type ConfigTranslateBudget struct {
+	Daily   ConfigTranslateBudgetLimit `yaml:"daily"`
+	Monthly ConfigTranslateBudgetLimit `yaml:"monthly"`
+}
+
+type ConfigTranslateBudgetLimit struct {
+	Soft int64 `yaml:"soft"` // Characters, only titles are translated when exceeded
+	Hard int64 `yaml:"hard"` // Characters, translation is stopped when exceeded
+}
+
+type ConfigTranslateValidate struct {
+	MinRatio        float64       `yaml:"min_ratio"` // Translated / source length, 0 to disable
+	MaxRatio        float64       `yaml:"max_ratio"`
+	RejectUnchanged bool          `yaml:"reject_unchanged"`
+	ErrorPatterns   []string      `yaml:"error_patterns"`
+	SuspiciousTTL   time.Duration `yaml:"suspicious_ttl"` // 0 to never use suspicious results
+}
+
+type ConfigTranslatePrewarm struct {
+	Interval    time.Duration                `yaml:"interval"`
+	Jitter      time.Duration                `yaml:"jitter"`
+	Concurrency int                          `yaml:"concurrency"` // Max feeds fetched simultaneously
+	Feeds       []ConfigTranslatePrewarmFeed `yaml:"feeds"`
+}
+
+type ConfigTranslatePrewarmFeed struct {
+	Path      string   `yaml:"path"`
+	Languages []string `yaml:"languages"`
+}
+
+type ConfigTranslateSegment struct {
+	Enable    bool `yaml:"enable"`
+	MaxChars  int  `yaml:"max_chars"`  // Max characters per request
+	BatchSize int  `yaml:"batch_size"` // Max segments per request
+}
+
+type ConfigTranslateLimit struct {
+	MaxConcurrency int     `yaml:"max_concurrency"`
+	RateLimit      float64 `yaml:"rate_limit"` // Requests per second
+	Burst          int     `yaml:"burst"`
 }
 
 type ConfigImageProxy struct {
 	Path  string                          `yaml:"path"`
-	Rules map[string]ConfigImageProxyRule `yaml:"rules"`
+	Rules map[string]ConfigImageProxyRule `yaml:"rules"` // By platform
+	Hosts map[string]ConfigImageProxyRule `yaml:"hosts"` // By source host glob, only request headers are applied
+
+	AllowPrivate bool                   `yaml:"allow_private"` // Allow private / loopback addresses, only for development
+	AllowHosts   []string               `yaml:"allow_hosts"`   // Host glob patterns allowed for platforms without own allowlist, empty for any
+	Sign         *ConfigImageProxySign  `yaml:"sign,omitempty"`
+	Compact      bool                   `yaml:"compact"` // Encode source in path as base64url instead of query
+	Cache        *ConfigImageProxyCache `yaml:"cache,omitempty"`
+
+	TransformMaxSize int64                     `yaml:"transform_max_size"` // Bytes, larger sources are not transformed
+	Attributes       map[string][]string       `yaml:"attributes"`         // Tag -> attributes to rewrite, "*" for all tags, empty for built-in rules
+	MaxSize          int64                     `yaml:"max_size"`           // Bytes, larger objects are refused, 0 for unlimited
+	Timeout          time.Duration             `yaml:"timeout"`            // Per request, including transferring body, 0 for unlimited
+	Include          []string                  `yaml:"include"`            // Source host globs to proxy, empty for all
+	Exclude          []string                  `yaml:"exclude"`            // Source host globs never proxied
+	SkipProxied      bool                      `yaml:"skip_proxied"`       // Keep links already pointing to this proxy untouched
+	BaseURL          string                    `yaml:"base_url"`           // Public base URL of proxy links, e.g. https://img.example.com, empty to derive from request
+	TrustForwarded   bool                      `yaml:"trust_forwarded"`    // Derive scheme and host from X-Forwarded-Proto / X-Forwarded-Host
+	Fallback         *ConfigImageProxyFallback `yaml:"fallback,omitempty"`
+}
+
+type ConfigImageProxyFallback struct {
+	Statuses    []int         `yaml:"statuses"`     // Upstream statuses treated as failure, empty for 403, 404, 410, 429, 500, 502, 503 and 504
+	Proxy       string        `yaml:"proxy"`        // Outbound proxy URL to retry through, e.g. http://127.0.0.1:8080 or socks5://127.0.0.1:1080
+	Placeholder string        `yaml:"placeholder"`  // Image file served when all attempts failed, empty to return upstream status
+	NegativeTTL time.Duration `yaml:"negative_ttl"` // Remember failed sources in memory, 0 to disable
+}
+
+type ConfigImageProxyCache struct {
+	Backend       string        `yaml:"backend"`         // disk or redis
+	Dir           string        `yaml:"dir"`             // For disk backend
+	MaxSize       int64         `yaml:"max_size"`        // Bytes, total size for disk backend
+	MaxObjectSize int64         `yaml:"max_object_size"` // Bytes, larger objects are streamed without caching
+	DefaultTTL    time.Duration `yaml:"default_ttl"`     // When upstream specifies no cache policy
+	ClientMaxAge  time.Duration `yaml:"client_max_age"`  // Cache-Control max-age sent to clients, 0 for remaining TTL
+}
+
+type ConfigImageProxySign struct {
+	Keys   []string      `yaml:"keys"`   // The first key signs, all keys are accepted
+	Expire time.Duration `yaml:"expire"` // 0 for never expire
 }
 
 type ConfigImageProxyRule struct {
-	Origin  *string `yaml:"origin"`
-	Referer *string `yaml:"referer"`
+	Origin    *string           `yaml:"origin"`
+	Referer   *string           `yaml:"referer"`
+	UserAgent *string           `yaml:"user_agent"`
+	Headers   map[string]string `yaml:"headers"` // Arbitrary request headers
+	Cookies   map[string]string `yaml:"cookies"` // Name -> value
+
+	AllowHosts []string              `yaml:"allow_hosts"`         // Host glob patterns allowed for this platform, replaces global one, requires sign
+	Transform  *ConfigImageTransform `yaml:"transform,omitempty"` // Default transform, overridden by query unless sign is configured
+	Attributes map[string][]string   `yaml:"attributes"`          // Replaces global attributes for this platform
+	Include    []string              `yaml:"include"`             // Replaces global include for this platform
+	Exclude    []string              `yaml:"exclude"`             // Added to global exclude for this platform
+
+	Alternatives []ConfigImageProxyRule `yaml:"alternatives"` // Request headers retried in order when fallback is enabled, each is applied over this rule
+}
+
+type ConfigImageTransform struct {
+	Width   int    `yaml:"width"`   // Max width, 0 for unlimited
+	Height  int    `yaml:"height"`  // Max height, 0 for unlimited
+	Fit     string `yaml:"fit"`     // contain (default), cover or fill
+	Quality int    `yaml:"quality"` // JPEG quality 1 ~ 100
+	Format  string `yaml:"format"`  // jpeg, png or webp, empty to keep source format
 }